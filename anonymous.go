@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// Modes of anonymous posting, which is disabled unless ANONYMOUS_POSTING is set to one of them
+const (
+	// Anonymous notes are hidden until a moderator dismisses their report
+	anonymousModerate = "moderate"
+	// Anonymous notes are published right away, marked as unverified
+	anonymousUnverified = "unverified"
+)
+
+// editTokenHeader carries the token that lets the author of an anonymous note edit or delete it
+const editTokenHeader = "X-Edit-Token"
+
+// createdNoteResponse is a new note, along with the edit token if the note is anonymous.
+type createdNoteResponse struct {
+	database.Note
+	EditToken          string `json:"edit_token,omitempty"`
+	EditTokenExpiresAt string `json:"edit_token_expires_at,omitempty"`
+}
+
+// issueEditToken stores a token for editing an anonymous note for a limited time.
+func (app *application) issueEditToken(ctx context.Context, noteID string) (token, expiresAt string, err error) {
+	now := time.Now().UTC()
+	if err := app.DB.DeleteExpiredEditTokens(ctx, now.Format(timestampFormat)); err != nil {
+		log.Printf("Failed to delete expired edit tokens: %s", err)
+	}
+
+	token, err = auth.GenerateEditToken()
+	if err != nil {
+		return "", "", err
+	}
+	expiresAt = now.Add(app.editTokenTTL).Format(timestampFormat)
+	err = app.DB.CreateEditToken(ctx, database.CreateEditTokenParams{
+		NoteID:    noteID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	return token, expiresAt, err
+}
+
+// validEditToken returns true if the request carries an unexpired edit token of the anonymous note.
+func (app *application) validEditToken(r *http.Request, note database.Note) bool {
+	token := r.Header.Get(editTokenHeader)
+	if token == "" || note.UserID != "" {
+		return false
+	}
+	stored, err := app.DB.GetEditToken(r.Context(), database.GetEditTokenParams{
+		NoteID:    note.ID,
+		ExpiresAt: time.Now().UTC().Format(timestampFormat),
+	})
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(stored.TokenHash), []byte(auth.HashToken(token))) == 1
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// createGuestNote posts a note without signing in and returns it with its edit token.
+func createGuestNote(t *testing.T, app *application, body string) createdNoteResponse {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	app.createNoteHandler(w, r, database.User{})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating a guest note responded with %d: %s", w.Code, w.Body)
+	}
+
+	var note createdNoteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &note); err != nil || note.EditToken == "" {
+		t.Fatalf("guest note came without an edit token: %s", w.Body)
+	}
+	return note
+}
+
+func editGuestNote(app *application, note createdNoteResponse, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, "/note/"+note.ID, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(editTokenHeader, note.EditToken)
+	r.SetPathValue("id", note.ID)
+	w := httptest.NewRecorder()
+	app.updateNoteHandler(w, r, database.User{})
+	return w
+}
+
+func TestGuestEditsNoteHeldForModeration(t *testing.T) {
+	app := newTestApp(t)
+	app.anonymousPosting = anonymousModerate
+	note := createGuestNote(t, app, `{"message":"Hello"}`)
+
+	if w := editGuestNote(app, note, `{"message":"Hello there"}`); w.Code != http.StatusOK {
+		t.Fatalf("update with the edit token responded with %d: %s", w.Code, w.Body)
+	}
+
+	r := httptest.NewRequest(http.MethodDelete, "/note/"+note.ID, nil)
+	r.SetPathValue("id", note.ID)
+	w := httptest.NewRecorder()
+	app.deleteNoteHandler(w, r, database.User{})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("delete without the edit token responded with %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	r.Header.Set(editTokenHeader, note.EditToken)
+	w = httptest.NewRecorder()
+	app.deleteNoteHandler(w, r, database.User{})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete with the edit token responded with %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestGuestNotesDontNotify(t *testing.T) {
+	app := newTestApp(t)
+	app.anonymousPosting = anonymousUnverified
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "Hello", "")
+
+	reply := createGuestNote(t, app, `{"message":"Hi @ann","parent_id":"`+note.ID+`"}`)
+	var count int
+	if err := app.conn.QueryRow("SELECT count(*) FROM notifications").Scan(&count); err != nil || count != 0 {
+		t.Errorf("guest reply stored %d notifications: %v", count, err)
+	}
+	mentions, err := app.DB.FetchNoteMentions(context.Background(), reply.ID)
+	if err != nil || len(mentions) != 0 {
+		t.Errorf("guest note stored mentions %v: %v", mentions, err)
+	}
+}
+
+func TestNotificationCountsLeaveOutUnknownActors(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "Hello", "")
+
+	// Notifications of guests stored before they were left out
+	err := app.DB.CreateNotification(ctx, database.CreateNotificationParams{
+		ID:     "phantom",
+		UserID: user.ID,
+		Type:   notificationReply,
+		NoteID: note.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := countTestNotifications(t, app, user); count != 0 {
+		t.Errorf("total = %d, want 0", count)
+	}
+	if unread, err := app.DB.CountUnreadNotifications(ctx, user.ID); err != nil || unread != 0 {
+		t.Errorf("unread = %d, want 0: %v", unread, err)
+	}
+}
+
+func TestGuestEditOfApprovedNoteIsHeld(t *testing.T) {
+	app := newTestApp(t)
+	app.anonymousPosting = anonymousModerate
+	moderator := createTestUser(t, app, "mod", "mod@example.com")
+	moderator.Role = userRoleModerator
+	note := createGuestNote(t, app, `{"message":"Hello"}`)
+	resolveTestReport(t, app, moderator, actionDismiss)
+
+	if w := editGuestNote(app, note, `{"message":"Buy now"}`); w.Code != http.StatusOK {
+		t.Fatalf("update with the edit token responded with %d: %s", w.Code, w.Body)
+	}
+	stored, err := app.DB.FetchNoteByID(context.Background(), note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Hidden == 0 {
+		t.Error("edit of an approved guest note went live without review")
+	}
+	// The moderator reviews the edit like a new note
+	resolveTestReport(t, app, moderator, actionDismiss)
+}
+
+func TestGuestsCanPostTheSameMessage(t *testing.T) {
+	app := newTestApp(t)
+	app.anonymousPosting = anonymousUnverified
+
+	createGuestNote(t, app, `{"message":"Thanks!"}`)
+	createGuestNote(t, app, `{"message":"Thanks!"}`)
+}
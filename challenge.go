@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/pow"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// powSolutionHeader carries the solution of a proof-of-work challenge as "<challenge>:<counter>"
+const powSolutionHeader = "X-PoW-Solution"
+
+// sqlitePowStore keeps used challenges in the database, so that processes sharing it
+// don't accept a challenge used with another one.
+type sqlitePowStore struct {
+	DB *database.Queries
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func (s *sqlitePowStore) Use(ctx context.Context, challenge string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	s.prune(ctx, now)
+
+	inserted, err := s.DB.UsePowChallenge(ctx, database.UsePowChallengeParams{
+		Challenge: challenge,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// prune deletes challenges that expired over a minute ago at most once a minute.
+// Expired challenges are refused before they are looked up, so they aren't needed.
+func (s *sqlitePowStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	if err := s.DB.DeleteExpiredPowChallenges(ctx, now.Add(-time.Minute).Unix()); err != nil {
+		log.Printf("Failed to delete expired challenges: %s", err)
+	}
+}
+
+// loadProofOfWork sets up proof-of-work challenges unless POW_DISABLED is "true". Challenges
+// are signed with POW_SECRET_KEY, or AUTH_SECRET_KEY if it isn't set, so that they stay valid
+// across restarts. Used challenges are kept in memory, or in the database if POW_STORE is "sqlite".
+func (app *application) loadProofOfWork() {
+	if os.Getenv("POW_DISABLED") == "true" {
+		return
+	}
+
+	key := os.Getenv("POW_SECRET_KEY")
+	if key == "" {
+		key = os.Getenv("AUTH_SECRET_KEY")
+	}
+	if key == "" {
+		log.Fatal("POW_SECRET_KEY or AUTH_SECRET_KEY must be set unless POW_DISABLED is true")
+	}
+
+	var store pow.Store
+	if os.Getenv("POW_STORE") == "sqlite" {
+		store = &sqlitePowStore{DB: app.DB}
+	} else {
+		store = pow.NewMemoryStore()
+	}
+	app.pow = pow.NewIssuer(
+		[]byte(key),
+		store,
+		utils.EnvInt("POW_BASE_DIFFICULTY", 16),
+		utils.EnvInt("POW_MAX_DIFFICULTY", 22),
+		utils.EnvInt("POW_THRESHOLD", 30),
+	)
+}
+
+// getChallengeHandler issues a proof-of-work challenge. Challenges get harder
+// as more of them are requested.
+func (app *application) getChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	if app.pow == nil {
+		http.Error(w, "Proof of work is disabled", http.StatusNotFound)
+		return
+	}
+
+	challenge, err := app.pow.Issue()
+	if err != nil {
+		log.Printf("Failed to issue a challenge: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, challenge)
+}
+
+// requireProofOfWork checks the solution of a challenge sent with the request.
+// It responds with an error and returns false if the solution is missing or wrong.
+func (app *application) requireProofOfWork(w http.ResponseWriter, r *http.Request) bool {
+	if app.pow == nil {
+		return true
+	}
+
+	solution := r.Header.Get(powSolutionHeader)
+	if solution == "" {
+		http.Error(w, "Solve a challenge from /challenge and send the solution in the "+powSolutionHeader+" header", http.StatusPreconditionRequired)
+		return false
+	}
+	if err := app.pow.Verify(r.Context(), solution); err != nil {
+		log.Printf("Rejected proof of work from %s: %s", clientIP(r), err)
+		http.Error(w, "Proof of work is not valid: "+err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
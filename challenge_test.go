@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/pow"
+)
+
+// solveTestChallenge looks for a counter that meets the difficulty of the challenge.
+func solveTestChallenge(c pow.Challenge) string {
+	for counter := 0; ; counter++ {
+		solution := c.Challenge + ":" + strconv.Itoa(counter)
+		hash := sha256.Sum256([]byte(solution))
+		zeros := 0
+		for _, b := range hash {
+			zeros += bits.LeadingZeros8(b)
+			if b != 0 {
+				break
+			}
+		}
+		if zeros >= c.Difficulty {
+			return solution
+		}
+	}
+}
+
+func TestSQLitePowStoreIsShared(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	first := pow.NewIssuer([]byte("secret"), &sqlitePowStore{DB: app.DB}, 4, 4, 100)
+	// Another process sharing the database
+	second := pow.NewIssuer([]byte("secret"), &sqlitePowStore{DB: app.DB}, 4, 4, 100)
+
+	c, err := first.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := solveTestChallenge(c)
+	if err := second.Verify(ctx, solution); err != nil {
+		t.Fatalf("challenge of another process was refused: %s", err)
+	}
+	if err := first.Verify(ctx, solution); err != pow.ErrUsed {
+		t.Errorf("challenge used by another process: err = %v, want %v", err, pow.ErrUsed)
+	}
+}
+
+func TestSQLitePowStorePrunesExpiredChallenges(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	store := &sqlitePowStore{DB: app.DB}
+	now := time.Now()
+
+	if _, err := store.Use(ctx, "old", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	store.lastPrune = time.Time{}
+	if ok, err := store.Use(ctx, "new", now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("new challenge was refused: %v", err)
+	}
+
+	var count int
+	if err := app.conn.QueryRow("SELECT count(*) FROM pow_challenges").Scan(&count); err != nil || count != 1 {
+		t.Errorf("store keeps %d challenges, want only the one that didn't expire: %v", count, err)
+	}
+}
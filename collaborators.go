@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// Roles a user can have on a note
+const (
+	roleOwner = "owner"
+	// Editors may update the note, but not delete it
+	roleEditor = "editor"
+	// Viewers may read the note even if it is private
+	roleViewer = "viewer"
+)
+
+// noteRole returns the role of the user on the note, or an empty string if the note
+// wasn't shared with the user. The user is empty for anonymous requests.
+func (app *application) noteRole(ctx context.Context, note database.Note, user database.User) string {
+	if user.ID == "" {
+		return ""
+	}
+	if note.UserID == user.ID {
+		return roleOwner
+	}
+
+	role, err := app.DB.GetCollaboratorRole(ctx, database.GetCollaboratorRoleParams{
+		NoteID: note.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to fetch role of user %q on note %q: %s", user.ID, note.ID, err)
+		}
+		return ""
+	}
+	return role
+}
+
+// fetchNoteForUser fetches the note from the path and the role of the user on it.
+// It responds with an error and returns false if the note doesn't exist, was deleted or the user can't see it.
+func (app *application) fetchNoteForUser(w http.ResponseWriter, r *http.Request, user database.User) (database.Note, string, bool) {
+	id := r.PathValue("id")
+
+	if !utils.ValidateId(id) {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return database.Note{}, "", false
+	}
+
+	note, err := app.DB.FetchNoteByID(r.Context(), id)
+	if err != nil || note.Deleted != 0 || !app.canViewNote(r.Context(), note, user) {
+		http.Error(w, "Note does not exist", http.StatusNotFound)
+		return database.Note{}, "", false
+	}
+
+	return note, app.noteRole(r.Context(), note, user), true
+}
+
+func (app *application) getCollaboratorsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role == "" {
+		http.Error(w, "Note belongs to another user", http.StatusForbidden)
+		return
+	}
+
+	collaborators, err := app.DB.FetchNoteCollaborators(r.Context(), note.ID)
+	if err != nil {
+		log.Printf("Failed to fetch collaborators of note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if collaborators == nil {
+		collaborators = []database.FetchNoteCollaboratorsRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total         int                                  `json:"total"`
+		Collaborators []database.FetchNoteCollaboratorsRow `json:"collaborators"`
+	}{
+		Total:         len(collaborators),
+		Collaborators: collaborators,
+	})
+}
+
+// addCollaboratorHandler shares the note with a user, or changes the role of an existing collaborator.
+func (app *application) addCollaboratorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role != roleOwner {
+		log.Printf("Unauthorized attempt to share a note %q by user %q", note.ID, user.ID)
+		http.Error(w, "Only the owner can share the note", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if body.Role == "" {
+		body.Role = roleViewer
+	}
+	if body.Role != roleViewer && body.Role != roleEditor {
+		http.Error(w, "Role must be either viewer or editor", http.StatusBadRequest)
+		return
+	}
+	if body.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+
+	collaborator, err := app.DB.GetUserByUsername(r.Context(), body.Username)
+	if err != nil {
+		http.Error(w, "User does not exist", http.StatusNotFound)
+		return
+	}
+	if collaborator.ID == user.ID {
+		http.Error(w, "You already own the note", http.StatusBadRequest)
+		return
+	}
+	if app.hasBlocked(r.Context(), collaborator.ID, user.ID) {
+		http.Error(w, errBlockedMsg, http.StatusForbidden)
+		return
+	}
+
+	added, err := app.DB.UpsertCollaborator(r.Context(), database.UpsertCollaboratorParams{
+		NoteID: note.ID,
+		UserID: collaborator.ID,
+		Role:   body.Role,
+	})
+	if err != nil {
+		log.Printf("Failed to share note %q with user %q: %s", note.ID, collaborator.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Note %q shared with user %q as %s", note.ID, collaborator.ID, added.Role)
+	respondWithJSON(w, http.StatusOK, database.FetchNoteCollaboratorsRow{
+		ID:        collaborator.ID,
+		Username:  collaborator.Username,
+		Name:      collaborator.Name,
+		Role:      added.Role,
+		CreatedAt: added.CreatedAt,
+	})
+}
+
+// removeCollaboratorHandler stops sharing the note with a user. Collaborators may remove themselves.
+func (app *application) removeCollaboratorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+
+	collaborator, err := app.DB.GetUserByUsername(r.Context(), r.PathValue("username"))
+	if err != nil {
+		http.Error(w, "User does not exist", http.StatusNotFound)
+		return
+	}
+	if role != roleOwner && collaborator.ID != user.ID {
+		log.Printf("Unauthorized attempt to unshare a note %q by user %q", note.ID, user.ID)
+		http.Error(w, "Only the owner can unshare the note", http.StatusForbidden)
+		return
+	}
+
+	deleted, err := app.DB.DeleteCollaborator(r.Context(), database.DeleteCollaboratorParams{
+		NoteID: note.ID,
+		UserID: collaborator.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to unshare note %q with user %q: %s", note.ID, collaborator.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Note is not shared with the user", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Note %q unshared with user %q", note.ID, collaborator.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSharedNotesHandler lists notes other users shared with the current user, along with the granted role.
+func (app *application) getSharedNotesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notes, err := app.DB.FetchSharedNotes(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch notes shared with user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if len(notes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	type sharedNote struct {
+		database.FetchSharedNotesRow
+		MessageHTML string `json:"message_html,omitempty"`
+	}
+	result := make([]sharedNote, len(notes))
+	for i, note := range notes {
+		result[i] = sharedNote{FetchSharedNotesRow: note}
+		if !wantsHTML(r) {
+			continue
+		}
+		result[i].MessageHTML, err = app.renderMessage(note.ID, note.Message, note.Format, note.Revision)
+		if err != nil {
+			log.Printf("Failed to render a note with the ID %q: %s", note.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total int          `json:"total"`
+		Notes []sharedNote `json:"notes"`
+	}{
+		Total: len(result),
+		Notes: result,
+	})
+}
@@ -16,6 +16,11 @@ func dbConnect(path string) (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup the database: %s", err)
 	}
+	// Add columns introduced after the tables were created
+	err = migrateDB(connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate the database: %s", err)
+	}
 	return connection, nil
 }
 
@@ -28,9 +33,25 @@ CREATE TABLE IF NOT EXISTS notes (
   updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
   user_id TEXT NOT NULL,
-  verified INTEGER NOT NULL DEFAULT 0
+  verified INTEGER NOT NULL DEFAULT 0,
+  visibility TEXT NOT NULL DEFAULT 'public' CHECK(
+    visibility IN ('public', 'unlisted', 'private')
+  ),
+  notebook_id TEXT NOT NULL DEFAULT '',
+  pinned INTEGER NOT NULL DEFAULT 0,
+  parent_id TEXT NOT NULL DEFAULT '',
+  deleted INTEGER NOT NULL DEFAULT 0,
+  hidden INTEGER NOT NULL DEFAULT 0,
+  format TEXT NOT NULL DEFAULT 'plain' CHECK(format IN ('plain', 'markdown')),
+  revision INTEGER NOT NULL DEFAULT 1
 );
 
+CREATE INDEX IF NOT EXISTS notes_created_at_idx ON notes (created_at, id);
+
+CREATE INDEX IF NOT EXISTS notes_user_id_idx ON notes (user_id, created_at);
+
+-- notes_parent_id_idx is created by migrateDB, once older databases have the parent_id column
+
 CREATE TRIGGER IF NOT EXISTS update_note_timestamp
 AFTER UPDATE ON notes
 FOR EACH ROW
@@ -40,6 +61,24 @@ BEGIN
   WHERE id = NEW.id;
 END;
 
+-- Notes created by users in the last day, kept after the notes are deleted so that
+-- deleting notes doesn't make room in the daily limit
+CREATE TABLE IF NOT EXISTS note_creations (
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS note_creations_user_id_idx ON note_creations (user_id, created_at);
+
+CREATE TRIGGER IF NOT EXISTS log_note_creation
+AFTER INSERT ON notes
+FOR EACH ROW WHEN NEW.user_id != ''
+BEGIN
+  DELETE FROM note_creations
+  WHERE user_id = NEW.user_id AND created_at < strftime('%Y-%m-%d %H:%M:%fZ', 'now', '-1 day');
+  INSERT INTO note_creations (user_id, created_at) VALUES (NEW.user_id, NEW.created_at);
+END;
+
 CREATE TABLE IF NOT EXISTS users (
   id TEXT NOT NULL PRIMARY KEY,
   email TEXT NOT NULL UNIQUE,
@@ -54,7 +93,9 @@ CREATE TABLE IF NOT EXISTS users (
   password TEXT NOT NULL,
   updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
-  verified INTEGER NOT NULL DEFAULT 0
+  verified INTEGER NOT NULL DEFAULT 0,
+  role TEXT NOT NULL DEFAULT 'user' CHECK(role IN ('user', 'moderator')),
+  suspended INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE TRIGGER IF NOT EXISTS update_user_timestamp
@@ -65,9 +106,365 @@ BEGIN
   SET updated_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
   WHERE id = NEW.id;
 END;
+
+CREATE TABLE IF NOT EXISTS lockouts (
+  id TEXT NOT NULL PRIMARY KEY,
+  scope TEXT NOT NULL CHECK(scope IN ('account', 'ip')),
+  subject TEXT NOT NULL,
+  ip_address TEXT NOT NULL,
+  failures INTEGER NOT NULL,
+  locked_until TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS lockouts_subject_idx ON lockouts (subject);
+
+CREATE TABLE IF NOT EXISTS two_factor (
+  user_id TEXT NOT NULL PRIMARY KEY,
+  secret TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  last_used_step INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  code_hash TEXT NOT NULL,
+  used_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  ip_address TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  last_seen_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  revoked_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS identities (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_id_idx ON identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL CHECK(
+    length(name) >= 2 AND
+    length(name) <= 50
+  ),
+  secret_hash TEXT NOT NULL,
+  redirect_uris TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS oauth_codes (
+  code_hash TEXT NOT NULL PRIMARY KEY,
+  client_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  redirect_uri TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  code_challenge TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS oauth_tokens (
+  id TEXT NOT NULL PRIMARY KEY,
+  token_hash TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL CHECK(kind IN ('access', 'refresh')),
+  grant_id TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  revoked_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS oauth_tokens_grant_id_idx ON oauth_tokens (grant_id);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  credential_id TEXT NOT NULL UNIQUE,
+  public_key BLOB NOT NULL,
+  sign_count INTEGER NOT NULL DEFAULT 0,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  last_used_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+  challenge TEXT NOT NULL PRIMARY KEY,
+  ceremony TEXT NOT NULL CHECK(ceremony IN ('registration', 'authentication')),
+  user_id TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS note_collaborators (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  role TEXT NOT NULL CHECK(role IN ('viewer', 'editor')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS note_collaborators_user_id_idx ON note_collaborators (user_id);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_collaborators
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_collaborators WHERE note_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS share_links (
+  id TEXT NOT NULL PRIMARY KEY,
+  note_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  password TEXT,
+  max_views INTEGER,
+  views INTEGER NOT NULL DEFAULT 0,
+  expires_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  revoked_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS share_links_note_id_idx ON share_links (note_id);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_share_links
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM share_links WHERE note_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS notebooks (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL CHECK(
+    length(name) >= 1 AND
+    length(name) <= 100
+  ),
+  description TEXT NOT NULL DEFAULT '',
+  position INTEGER NOT NULL DEFAULT 0,
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_idx ON notebooks (user_id);
+
+CREATE TRIGGER IF NOT EXISTS update_notebook_timestamp
+AFTER UPDATE ON notebooks
+FOR EACH ROW
+BEGIN
+  UPDATE notebooks
+  SET updated_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+  WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_notebook_notes
+AFTER DELETE ON notebooks
+FOR EACH ROW
+BEGIN
+  UPDATE notes SET notebook_id = '' WHERE notebook_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS favorites (
+  user_id TEXT NOT NULL,
+  note_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (user_id, note_id)
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_favorites
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM favorites WHERE note_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS note_reactions (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  emoji TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id, emoji)
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_reactions
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_reactions WHERE note_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS mentions (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  type TEXT NOT NULL CHECK(type IN ('mention', 'reply', 'reaction')),
+  actor_id TEXT NOT NULL,
+  note_id TEXT NOT NULL,
+  emoji TEXT NOT NULL DEFAULT '',
+  read INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_notifications
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM mentions WHERE note_id = OLD.id;
+  DELETE FROM notifications WHERE note_id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS follows (
+  follower_id TEXT NOT NULL,
+  followee_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (follower_id, followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_id_idx ON follows (followee_id);
+
+CREATE TABLE IF NOT EXISTS user_restrictions (
+  user_id TEXT NOT NULL,
+  target_id TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK(kind IN ('mute', 'block')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (user_id, target_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS reports (
+  id TEXT NOT NULL PRIMARY KEY,
+  note_id TEXT NOT NULL,
+  reporter_id TEXT NOT NULL,
+  reason TEXT NOT NULL CHECK(
+    reason IN ('spam', 'harassment', 'hate', 'sexual', 'violence', 'other')
+  ),
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open' CHECK(status IN ('open', 'dismissed', 'resolved')),
+  action TEXT NOT NULL DEFAULT '',
+  moderator_id TEXT NOT NULL DEFAULT '',
+  moderator_note TEXT NOT NULL DEFAULT '',
+  resolved_at TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (note_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, created_at);
+
+CREATE TABLE IF NOT EXISTS spam_tokens (
+  token TEXT NOT NULL PRIMARY KEY,
+  spam INTEGER NOT NULL DEFAULT 0,
+  ham INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS spam_documents (
+  class TEXT NOT NULL PRIMARY KEY CHECK(class IN ('spam', 'ham')),
+  documents INTEGER NOT NULL DEFAULT 0
+);
+
+-- Token buckets of the rate limiter, updated_at is Unix time in seconds
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key TEXT NOT NULL PRIMARY KEY,
+  tokens REAL NOT NULL,
+  allowed INTEGER NOT NULL,
+  updated_at REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS note_edit_tokens (
+  note_id TEXT NOT NULL PRIMARY KEY,
+  token_hash TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_edit_tokens
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_edit_tokens WHERE note_id = OLD.id;
+END;
+
+-- Used proof-of-work challenges until they expire, expires_at is Unix time in seconds
+CREATE TABLE IF NOT EXISTS pow_challenges (
+  challenge TEXT NOT NULL PRIMARY KEY,
+  expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pow_challenges_expires_at_idx ON pow_challenges (expires_at);
 `)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// migrateDB brings tables created by older versions up to date with setupDB.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so every column added
+// to an existing table has to be listed here as well. Indexes on such columns are
+// created here too, once the columns exist.
+func migrateDB(conn *sql.DB) error {
+	migrations := []struct{ table, column, definition string }{
+		{"notes", "visibility", `TEXT NOT NULL DEFAULT 'public' CHECK(visibility IN ('public', 'unlisted', 'private'))`},
+		{"notes", "notebook_id", `TEXT NOT NULL DEFAULT ''`},
+		{"notes", "pinned", `INTEGER NOT NULL DEFAULT 0`},
+		{"notes", "parent_id", `TEXT NOT NULL DEFAULT ''`},
+		{"notes", "deleted", `INTEGER NOT NULL DEFAULT 0`},
+		{"notes", "hidden", `INTEGER NOT NULL DEFAULT 0`},
+		{"notes", "format", `TEXT NOT NULL DEFAULT 'plain' CHECK(format IN ('plain', 'markdown'))`},
+		{"notes", "revision", `INTEGER NOT NULL DEFAULT 1`},
+		{"users", "role", `TEXT NOT NULL DEFAULT 'user' CHECK(role IN ('user', 'moderator'))`},
+		{"users", "suspended", `INTEGER NOT NULL DEFAULT 0`},
+	}
+	for _, m := range migrations {
+		if err := addColumnIfMissing(conn, m.table, m.column, m.definition); err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Exec(`
+CREATE INDEX IF NOT EXISTS notes_parent_id_idx ON notes (parent_id, created_at);
+`)
+	return err
+}
+
+func addColumnIfMissing(conn *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := conn.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	_, err = conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// baselineSchema is the schema of the first release, before any migrations.
+const baselineSchema = `
+CREATE TABLE notes (
+  id TEXT NOT NULL PRIMARY KEY,
+  author TEXT NOT NULL,
+  message TEXT NOT NULL,
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  user_id TEXT NOT NULL,
+  verified INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE users (
+  id TEXT NOT NULL PRIMARY KEY,
+  email TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  username TEXT NOT NULL UNIQUE,
+  password TEXT NOT NULL,
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  verified INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO users (id, email, name, username, password) VALUES ('u1', 'ann@example.com', 'Ann', 'ann', 'x');
+INSERT INTO notes (id, author, message, user_id) VALUES ('n1', 'Ann', 'Hello', 'u1');
+`
+
+func TestDBConnectMigratesBaselineSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annynotes.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(baselineSchema); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	conn, err := dbConnect(path)
+	if err != nil {
+		t.Fatalf("failed to open a database of the first release: %s", err)
+	}
+	defer conn.Close()
+
+	var index int
+	if err := conn.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'notes_parent_id_idx'`).Scan(&index); err != nil || index != 1 {
+		t.Errorf("index on parent_id wasn't created: %v", err)
+	}
+
+	db := database.New(conn)
+	note, err := db.FetchNoteByID(context.Background(), "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Visibility != visibilityPublic || note.ParentID != "" || note.Format != formatPlain {
+		t.Errorf("existing note wasn't migrated: %+v", note)
+	}
+	user, err := db.GetUserByID(context.Background(), "u1")
+	if err != nil || user.Role != userRoleUser {
+		t.Errorf("existing user wasn't migrated: %+v %v", user, err)
+	}
+
+	_, err = db.CreateNote(context.Background(), database.CreateNoteParams{
+		ID:         "n2",
+		Author:     "Ann",
+		Message:    "Reply",
+		UserID:     "u1",
+		Visibility: visibilityPublic,
+		ParentID:   "n1",
+		Format:     formatPlain,
+	})
+	if err != nil {
+		t.Errorf("failed to create a note in the migrated database: %s", err)
+	}
+
+	// Opening the migrated database again is a no-op
+	if conn, err := dbConnect(path); err != nil {
+		t.Errorf("failed to reopen the migrated database: %s", err)
+	} else {
+		conn.Close()
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// setPinned returns a handler pinning or unpinning a note. Pinned notes are listed first.
+func (app *application) setPinned(pinned bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		note, role, ok := app.fetchNoteForUser(w, r, user)
+		if !ok {
+			return
+		}
+		if role != roleOwner {
+			log.Printf("Unauthorized attempt to pin a note %q by user %q", note.ID, user.ID)
+			http.Error(w, "Only the owner can pin the note", http.StatusForbidden)
+			return
+		}
+
+		var value int64
+		if pinned {
+			value = 1
+		}
+		err := app.DB.SetNotePinned(r.Context(), database.SetNotePinnedParams{
+			Pinned: value,
+			ID:     note.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to pin a note with the ID %q: %s", note.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// addFavoriteHandler bookmarks a note the user can see. Adding a favorite twice has no effect.
+func (app *application) addFavoriteHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, _, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := app.DB.AddFavorite(r.Context(), database.AddFavoriteParams{
+		UserID: user.ID,
+		NoteID: note.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to favorite note %q by user %q: %s", note.ID, user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteFavoriteHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	err := app.DB.DeleteFavorite(r.Context(), database.DeleteFavoriteParams{
+		UserID: user.ID,
+		NoteID: id,
+	})
+	if err != nil {
+		log.Printf("Failed to unfavorite note %q by user %q: %s", id, user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getFavoritesHandler lists favorite notes, most recently added first.
+// Notes that have since become private to the user are left out.
+func (app *application) getFavoritesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notes, err := app.DB.FetchFavoriteNotes(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch favorites of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if len(notes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	result, err := app.noteResponses(r.Context(), notes, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total int            `json:"total"`
+		Notes []noteResponse `json:"notes"`
+	}{
+		Total: len(result),
+		Notes: result,
+	})
+}
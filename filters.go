@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/filter"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// spamStore keeps what the spam classifier has learned in the database.
+type spamStore struct {
+	DB *database.Queries
+}
+
+func (s spamStore) Documents(ctx context.Context) (spam, ham int64, err error) {
+	rows, err := s.DB.FetchSpamDocuments(ctx)
+	for _, row := range rows {
+		if row.Class == "spam" {
+			spam = row.Documents
+		} else {
+			ham = row.Documents
+		}
+	}
+	return spam, ham, err
+}
+
+func (s spamStore) Tokens(ctx context.Context, tokens []string) (map[string]filter.TokenCounts, error) {
+	counts := make(map[string]filter.TokenCounts)
+	for _, t := range tokens {
+		row, err := s.DB.GetSpamToken(ctx, t)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		counts[t] = filter.TokenCounts{Spam: row.Spam, Ham: row.Ham}
+	}
+	return counts, nil
+}
+
+func (s spamStore) Train(ctx context.Context, tokens []string, spam bool) error {
+	params := database.AddSpamTokenParams{Spam: 1}
+	class := "spam"
+	if !spam {
+		params = database.AddSpamTokenParams{Ham: 1}
+		class = "ham"
+	}
+	for _, t := range tokens {
+		params.Token = t
+		if err := s.DB.AddSpamToken(ctx, params); err != nil {
+			return err
+		}
+	}
+	return s.DB.AddSpamDocument(ctx, class)
+}
+
+// loadContentFilters builds the pipeline notes go through on create and update.
+// The profanity filter is enabled by listing words in PROFANITY_WORDS.
+func (app *application) loadContentFilters() {
+	app.spamClassifier = &filter.Bayes{
+		Store:        spamStore{DB: app.DB},
+		Threshold:    float64(utils.EnvInt("SPAM_THRESHOLD_PERCENT", 90)) / 100,
+		MinDocuments: int64(utils.EnvInt("SPAM_MIN_DOCUMENTS", 10)),
+	}
+
+	var pipeline filter.Pipeline
+	mode := filter.ProfanityMode(os.Getenv("PROFANITY_MODE"))
+	if mode != filter.ProfanityReject && mode != filter.ProfanityFlag {
+		mode = filter.ProfanityMask
+	}
+	if profanity := filter.NewProfanity(strings.Split(os.Getenv("PROFANITY_WORDS"), ","), mode); profanity != nil {
+		pipeline = append(pipeline, profanity)
+	}
+	pipeline = append(pipeline,
+		&filter.Repeats{
+			MaxRun: utils.EnvInt("FILTER_MAX_REPEATED_CHARS", 10),
+			Recent: func(ctx context.Context, note *filter.Note) ([]string, error) {
+				// Guests have no identity, so notes of different guests can't be told apart
+				if note.AuthorID == "" {
+					return nil, nil
+				}
+				return app.DB.FetchRecentMessages(ctx, database.FetchRecentMessagesParams{
+					UserID: note.AuthorID,
+					ID:     note.ID,
+				})
+			},
+		},
+		&filter.Links{Max: utils.EnvInt("FILTER_MAX_LINKS", 3)},
+		app.spamClassifier,
+	)
+	app.contentFilters = pipeline
+}
+
+// checkContent runs the content filters on a message written by the user. It returns the message
+// as modified by the filters along with the verdict, or responds with an error if the note was rejected.
+func (app *application) checkContent(w http.ResponseWriter, r *http.Request, user database.User, id, message string) (string, filter.Verdict, bool) {
+	note := filter.Note{ID: id, AuthorID: user.ID, Message: message}
+	verdict, err := app.contentFilters.Run(r.Context(), &note)
+	if err != nil {
+		log.Printf("Failed to filter a note by user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return "", verdict, false
+	}
+	if verdict.Action == filter.Reject {
+		log.Printf("Note by user %q was rejected by the %s filter: %s", user.ID, verdict.Filter, verdict.Reason)
+		http.Error(w, verdict.Reason, http.StatusBadRequest)
+		return "", verdict, false
+	}
+	return note.Message, verdict, true
+}
+
+// flagNote hides a note until a moderator reviews it. The report is made in the name of no user.
+func (app *application) flagNote(ctx context.Context, noteID, reason, details string) error {
+	err := app.DB.SetNoteHidden(ctx, database.SetNoteHiddenParams{Hidden: 1, ID: noteID})
+	if err != nil {
+		return err
+	}
+	err = app.DB.FlagNote(ctx, database.FlagNoteParams{
+		ID:      utils.GenerateUniqueId(),
+		NoteID:  noteID,
+		Reason:  reason,
+		Details: details,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Note %q was flagged: %s", noteID, details)
+	return nil
+}
+
+// flagFilteredNote hides a note flagged by a content filter until a moderator reviews it.
+func (app *application) flagFilteredNote(ctx context.Context, noteID string, verdict filter.Verdict) error {
+	return app.flagNote(ctx, noteID, verdict.Category, fmt.Sprintf("Flagged by the %s filter: %s", verdict.Filter, verdict.Reason))
+}
+
+// trainSpamHandler teaches the spam classifier that a message is spam or not.
+func (app *application) trainSpamHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Message string `json:"message"`
+		Spam    bool   `json:"spam"`
+	}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" {
+		http.Error(w, "Message field cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.spamClassifier.Train(r.Context(), body.Message, body.Spam); err != nil {
+		log.Printf("Failed to train the spam classifier: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// setFollowing returns a handler following or unfollowing the user from the path. Both are idempotent.
+func (app *application) setFollowing(follow bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		followee, err := app.DB.GetUserByUsername(r.Context(), r.PathValue("username"))
+		if err != nil {
+			http.Error(w, "User does not exist", http.StatusNotFound)
+			return
+		}
+		if followee.ID == user.ID {
+			http.Error(w, "You can't follow yourself", http.StatusBadRequest)
+			return
+		}
+		if follow && app.hasBlocked(r.Context(), followee.ID, user.ID) {
+			http.Error(w, errBlockedMsg, http.StatusForbidden)
+			return
+		}
+
+		if follow {
+			err = app.DB.Follow(r.Context(), database.FollowParams{
+				FollowerID: user.ID,
+				FolloweeID: followee.ID,
+			})
+		} else {
+			err = app.DB.Unfollow(r.Context(), database.UnfollowParams{
+				FollowerID: user.ID,
+				FolloweeID: followee.ID,
+			})
+		}
+		if err != nil {
+			log.Printf("Failed to update follow of user %q by user %q: %s", followee.ID, user.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type followResponse struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	FollowedAt string `json:"followed_at"`
+}
+
+// getFollowsHandler returns a handler listing followers of the user from the path,
+// or users they follow, most recent first.
+func (app *application) getFollowsHandler(followers bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := app.DB.GetUserByUsername(r.Context(), r.PathValue("username"))
+		if err != nil {
+			http.Error(w, "User does not exist", http.StatusNotFound)
+			return
+		}
+
+		limit, offset, err := parsePage(r, 50, 200)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var rows []database.FetchFollowersRow
+		var total int64
+		if followers {
+			rows, err = app.DB.FetchFollowers(r.Context(), database.FetchFollowersParams{
+				FolloweeID: user.ID,
+				Limit:      int64(limit),
+				Offset:     int64(offset),
+			})
+			if err == nil {
+				total, err = app.DB.CountFollowers(r.Context(), user.ID)
+			}
+		} else {
+			var following []database.FetchFollowingRow
+			following, err = app.DB.FetchFollowing(r.Context(), database.FetchFollowingParams{
+				FollowerID: user.ID,
+				Limit:      int64(limit),
+				Offset:     int64(offset),
+			})
+			for _, row := range following {
+				rows = append(rows, database.FetchFollowersRow(row))
+			}
+			if err == nil {
+				total, err = app.DB.CountFollowing(r.Context(), user.ID)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to fetch follows of user %q: %s", user.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]followResponse, len(rows))
+		for i, row := range rows {
+			users[i] = followResponse{
+				ID:         row.ID,
+				Username:   row.Username,
+				Name:       row.Name,
+				FollowedAt: row.CreatedAt,
+			}
+		}
+
+		respondWithJSON(w, http.StatusOK, &struct {
+			Total int64            `json:"total"`
+			Users []followResponse `json:"users"`
+		}{
+			Total: total,
+			Users: users,
+		})
+	}
+}
+
+// Feed cursors point at the last note of the previous page, which is identified by its
+// creation time and ID, so that pages stay stable while new notes are posted.
+func encodeFeedCursor(note database.Note) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(note.CreatedAt + "|" + note.ID))
+}
+
+func decodeFeedCursor(cursor string) (createdAt, id string, ok bool) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", false
+	}
+	createdAt, id, ok = strings.Cut(string(b), "|")
+	return createdAt, id, ok
+}
+
+// getFeedHandler lists public notes of followed users, newest first.
+// The next page is requested with ?cursor set to next_cursor of the previous one.
+func (app *application) getFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > 100 {
+			http.Error(w, "Limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Start from the newest note unless a cursor is given
+	beforeCreatedAt, beforeID := "9999-12-31", ""
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var ok bool
+		beforeCreatedAt, beforeID, ok = decodeFeedCursor(cursor)
+		if !ok {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	notes, err := app.DB.FetchFeed(r.Context(), database.FetchFeedParams{
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		FollowerID:      user.ID,
+		Limit:           int64(limit),
+	})
+	if err != nil {
+		log.Printf("Failed to fetch feed of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := app.noteResponses(r.Context(), notes, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(notes) == limit {
+		nextCursor = encodeFeedCursor(notes[len(notes)-1])
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Notes      []noteResponse `json:"notes"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}{
+		Notes:      result,
+		NextCursor: nextCursor,
+	})
+}
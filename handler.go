@@ -1,21 +1,62 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"net/http"
 	"net/mail"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chtozamm/annynotes-go/internal/auth"
 	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/filter"
 	"github.com/chtozamm/annynotes-go/internal/utils"
 	"github.com/mattn/go-sqlite3"
 )
 
-func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request) {
+// Note visibility
+const (
+	// Public notes are listed and readable by anyone
+	visibilityPublic = "public"
+	// Unlisted notes are readable by anyone who knows the ID, but are not listed
+	visibilityUnlisted = "unlisted"
+	// Private notes are readable only by their owner
+	visibilityPrivate = "private"
+)
+
+func validVisibility(visibility string) bool {
+	switch visibility {
+	case visibilityPublic, visibilityUnlisted, visibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// canViewNote returns true if the user may read the note. The user is empty for anonymous requests.
+// Notes hidden by moderation are visible only to their owner and moderators.
+func (app *application) canViewNote(ctx context.Context, note database.Note, user database.User) bool {
+	if note.Hidden != 0 && app.noteRole(ctx, note, user) != roleOwner && !isModerator(user) {
+		return false
+	}
+	if note.Visibility != visibilityPrivate || app.noteRole(ctx, note, user) != "" {
+		return true
+	}
+	// Replies to a private note are visible to everyone who can see the note
+	if note.ParentID != "" {
+		parent, err := app.DB.FetchNoteByID(ctx, note.ParentID)
+		return err == nil && app.canViewNote(ctx, parent, user)
+	}
+	return false
+}
+
+// Listings include public notes and all notes of the user who requested them.
+func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	var notes []database.Note
 	var err error
 
@@ -23,9 +64,9 @@ func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request)
 	sortQuery := r.URL.Query().Get("sort")
 	switch strings.ToLower(sortQuery) {
 	case "desc":
-		notes, err = app.DB.FetchNotesDESC(r.Context())
+		notes, err = app.DB.FetchNotesDESC(r.Context(), user.ID)
 	default:
-		notes, err = app.DB.FetchNotes(r.Context())
+		notes, err = app.DB.FetchNotes(r.Context(), user.ID)
 	}
 
 	if err != nil {
@@ -39,12 +80,19 @@ func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	result, err := app.noteResponses(r.Context(), notes, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
 	payload, err := json.Marshal(&struct {
-		Total int             `json:"total"`
-		Notes []database.Note `json:"notes"`
+		Total int            `json:"total"`
+		Notes []noteResponse `json:"notes"`
 	}{
-		Total: len(notes),
-		Notes: notes,
+		Total: len(result),
+		Notes: result,
 	})
 	if err != nil {
 		log.Printf("Failed to marshal notes: %s", err)
@@ -56,7 +104,7 @@ func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request)
 	w.Write(payload)
 }
 
-func (app *application) getNotesFromAuthorHandler(w http.ResponseWriter, r *http.Request) {
+func (app *application) getNotesFromAuthorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	author := r.PathValue("author")
 	if author == "" {
 		msg := "Author was not provided"
@@ -75,9 +123,15 @@ func (app *application) getNotesFromAuthorHandler(w http.ResponseWriter, r *http
 	sortQuery := r.URL.Query().Get("sort")
 	switch strings.ToLower(sortQuery) {
 	case "desc":
-		notes, err = app.DB.FetchNotesFromAuthorDESC(r.Context(), author)
+		notes, err = app.DB.FetchNotesFromAuthorDESC(r.Context(), database.FetchNotesFromAuthorDESCParams{
+			Author:   author,
+			ViewerID: user.ID,
+		})
 	default:
-		notes, err = app.DB.FetchNotesFromAuthor(r.Context(), author)
+		notes, err = app.DB.FetchNotesFromAuthor(r.Context(), database.FetchNotesFromAuthorParams{
+			Author:   author,
+			ViewerID: user.ID,
+		})
 	}
 
 	// Handle no content response
@@ -102,12 +156,19 @@ func (app *application) getNotesFromAuthorHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	result, err := app.noteResponses(r.Context(), notes, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
 	payload, err := json.Marshal(&struct {
-		Total int             `json:"total"`
-		Notes []database.Note `json:"notes"`
+		Total int            `json:"total"`
+		Notes []noteResponse `json:"notes"`
 	}{
-		Total: len(notes),
-		Notes: notes,
+		Total: len(result),
+		Notes: result,
 	})
 	if err != nil {
 		log.Printf("Failed to marshal notes: %s", err)
@@ -119,7 +180,7 @@ func (app *application) getNotesFromAuthorHandler(w http.ResponseWriter, r *http
 	w.Write(payload)
 }
 
-func (app *application) getNoteHandler(w http.ResponseWriter, r *http.Request) {
+func (app *application) getNoteHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	id := r.PathValue("id")
 
 	if id == "" {
@@ -135,8 +196,20 @@ func (app *application) getNoteHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
+	// Don't reveal that a private note exists
+	if !app.canViewNote(r.Context(), note, user) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 
-	payload, err := json.Marshal(&note)
+	result, err := app.noteResponses(r.Context(), []database.Note{note}, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions of note %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(&result[0])
 	if err != nil {
 		log.Printf("Failed to marshal note: %s", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -148,6 +221,16 @@ func (app *application) getNoteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	anonymous := user.ID == ""
+	if anonymous && app.anonymousPosting == "" {
+		http.Error(w, "Sign in to post notes", http.StatusUnauthorized)
+		return
+	}
+
+	// Unverified users and guests could be bots, so they have to prove some work first
+	if user.Verified == 0 && !app.requireProofOfWork(w, r) {
+		return
+	}
 
 	var note database.Note
 
@@ -174,6 +257,52 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 		note.Author = "stranger"
 	}
 
+	// Replies can be seen by whoever can see the note they reply to
+	var parent *database.Note
+	if note.ParentID != "" {
+		parentNote, err := app.DB.FetchNoteByID(r.Context(), note.ParentID)
+		if err != nil || parentNote.Deleted != 0 || !app.canViewNote(r.Context(), parentNote, user) {
+			http.Error(w, "Parent note does not exist", http.StatusBadRequest)
+			return
+		}
+		if app.hasBlocked(r.Context(), parentNote.UserID, user.ID) {
+			http.Error(w, errBlockedMsg, http.StatusForbidden)
+			return
+		}
+		if note.Visibility != "" && note.Visibility != parentNote.Visibility {
+			http.Error(w, "Replies have the visibility of the note they reply to", http.StatusBadRequest)
+			return
+		}
+		note.Visibility = parentNote.Visibility
+		parent = &parentNote
+	}
+
+	if note.Visibility == "" {
+		note.Visibility = visibilityPublic
+	}
+	if !validVisibility(note.Visibility) {
+		http.Error(w, "Visibility must be one of public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+	// Nobody but moderators could read private notes of guests
+	if anonymous && note.Visibility == visibilityPrivate {
+		http.Error(w, "Anonymous notes can't be private", http.StatusBadRequest)
+		return
+	}
+
+	if note.NotebookID != "" && !app.ownsNotebook(r, user, note.NotebookID) {
+		http.Error(w, "Notebook does not exist", http.StatusBadRequest)
+		return
+	}
+
+	if note.Format == "" {
+		note.Format = formatPlain
+	}
+	if !validFormat(note.Format) {
+		http.Error(w, "Format must be one of plain or markdown", http.StatusBadRequest)
+		return
+	}
+
 	note.ID = utils.GenerateUniqueId()
 
 	if !utils.ValidateId(note.ID) {
@@ -183,25 +312,68 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	newNote, err := app.DB.CreateNote(r.Context(), database.CreateNoteParams{
-		ID:       note.ID,
-		Author:   note.Author,
-		Message:  note.Message,
-		UserID:   user.ID,
-		Verified: user.Verified,
+	message, verdict, ok := app.checkContent(w, r, user, note.ID, note.Message)
+	if !ok {
+		return
+	}
+	note.Message = message
+
+	newNote, ok := app.createNoteWithinQuota(w, r, user, database.CreateNoteParams{
+		ID:         note.ID,
+		Author:     note.Author,
+		Message:    note.Message,
+		UserID:     user.ID,
+		Verified:   user.Verified,
+		Visibility: note.Visibility,
+		NotebookID: note.NotebookID,
+		ParentID:   note.ParentID,
+		Format:     note.Format,
 	})
+	if !ok {
+		return
+	}
+
+	// Flagged notes stay hidden until a moderator reviews them
+	flagged := true
+	switch {
+	case verdict.Action == filter.Flag:
+		err = app.flagFilteredNote(r.Context(), newNote.ID, verdict)
+	case anonymous && app.anonymousPosting == anonymousModerate:
+		err = app.flagNote(r.Context(), newNote.ID, "other", "Anonymous note held for moderation")
+	default:
+		flagged = false
+	}
 	if err != nil {
-		log.Printf("Failed to create a new note: %s", err)
+		log.Printf("Failed to flag a note with the ID %q: %s", newNote.ID, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	if flagged {
+		newNote.Hidden = 1
+	}
+
+	// Guests can't sign in, so they get a token to fix or take down the note for a while
+	response := createdNoteResponse{Note: newNote}
+	if anonymous {
+		response.EditToken, response.EditTokenExpiresAt, err = app.issueEditToken(r.Context(), newNote.ID)
+		if err != nil {
+			log.Printf("Failed to issue an edit token for a note with the ID %q: %s", newNote.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
 
-	payload, err := json.Marshal(&newNote)
+	payload, err := json.Marshal(&response)
 	if err != nil {
 		log.Printf("Failed to marshal note: %s", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	app.updateMentions(r.Context(), newNote, user)
+	if parent != nil && newNote.Hidden == 0 {
+		app.notify(r.Context(), parent.UserID, user.ID, notificationReply, newNote.ID, "")
+	}
+
 	log.Printf("New note created with the ID %q", note.ID)
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
@@ -231,13 +403,45 @@ func (app *application) deleteNoteHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Note does not exist", http.StatusNotFound)
 		return
 	}
-	if note.UserID != user.ID {
+	// Guests can take down their notes while they are held for moderation
+	editToken := app.validEditToken(r, note)
+	if !editToken && !app.canViewNote(r.Context(), note, user) {
+		log.Printf("Attempt to delete a private note %q by user %q", note.ID, user.ID)
+		http.Error(w, "Note does not exist", http.StatusNotFound)
+		return
+	}
+	if !editToken && app.noteRole(r.Context(), note, user) != roleOwner {
 		log.Printf("Unauthorized attempt to delete a note %q by user %q", note.ID, user.ID)
 		http.Error(w, "Note belongs to another user", http.StatusUnauthorized)
 		return
 	}
 
-	err = app.DB.DeleteNote(r.Context(), id)
+	// A note with replies is replaced by a placeholder to keep the thread together,
+	// unless a hard delete was requested, which removes all replies as well. Only moderators
+	// can hard delete threads that have replies of other users.
+	hard := r.URL.Query().Get("hard") == "true"
+	if note.Deleted != 0 && !hard {
+		http.Error(w, "Note does not exist", http.StatusNotFound)
+		return
+	}
+
+	replies, err := app.DB.CountReplies(r.Context(), id)
+	if err != nil {
+		log.Printf("Failed to count replies to a note with the ID %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if replies > 0 && !hard {
+		err = app.DB.SoftDeleteNote(r.Context(), id)
+	} else {
+		err = app.removeThread(r.Context(), note, isModerator(user))
+	}
+	if errors.Is(err, errThreadHasOtherAuthors) {
+		log.Printf("Attempt to hard delete a note %q with replies of other users by user %q", id, user.ID)
+		http.Error(w, "Note has replies of other users", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to delete a note with the ID %q: %s", id, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -266,12 +470,25 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	note, err := app.DB.FetchNoteByID(r.Context(), id)
-	if err != nil {
+	if err != nil || note.Deleted != 0 {
 		log.Printf("Attempt to update a non-existing note with the ID: %q", id)
 		http.Error(w, "Note does not exist", http.StatusNotFound)
 		return
 	}
-	if note.UserID != user.ID {
+	// Guests can fix their notes while they are held for moderation
+	editToken := app.validEditToken(r, note)
+	if !editToken && !app.canViewNote(r.Context(), note, user) {
+		log.Printf("Attempt to update a private note %q by user %q", note.ID, user.ID)
+		http.Error(w, "Note does not exist", http.StatusNotFound)
+		return
+	}
+	// Editors may change the note, but only the owner decides who can see it.
+	// Authors of anonymous notes edit them as editors, as the notes have no owner.
+	role := app.noteRole(r.Context(), note, user)
+	if role == "" && editToken {
+		role = roleEditor
+	}
+	if role != roleOwner && role != roleEditor {
 		log.Printf("Unauthorized attempt to update a note %q by user %q", note.ID, user.ID)
 		http.Error(w, "Note belongs to another user", http.StatusUnauthorized)
 		return
@@ -302,16 +519,119 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 		newNote.Author = note.Author
 	}
 
+	if newNote.Visibility == "" {
+		newNote.Visibility = note.Visibility
+	}
+	if !validVisibility(newNote.Visibility) {
+		http.Error(w, "Visibility must be one of public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+	if newNote.Visibility != note.Visibility && role != roleOwner {
+		http.Error(w, "Only the owner can change visibility of the note", http.StatusForbidden)
+		return
+	}
+	if newNote.Visibility != note.Visibility && note.ParentID != "" {
+		http.Error(w, "Replies have the visibility of the note they reply to", http.StatusBadRequest)
+		return
+	}
+	if newNote.Format == "" {
+		newNote.Format = note.Format
+	}
+	if !validFormat(newNote.Format) {
+		http.Error(w, "Format must be one of plain or markdown", http.StatusBadRequest)
+		return
+	}
+	if newNote.ParentID != "" && newNote.ParentID != note.ParentID {
+		http.Error(w, "Replies can't be moved to another note", http.StatusBadRequest)
+		return
+	}
+
+	// Notebooks are personal, so only the owner can move the note
+	moved := newNote.NotebookID != "" && newNote.NotebookID != note.NotebookID
+	if moved && role != roleOwner {
+		http.Error(w, "Only the owner can move the note", http.StatusForbidden)
+		return
+	}
+	if moved && !app.ownsNotebook(r, user, newNote.NotebookID) {
+		http.Error(w, "Notebook does not exist", http.StatusBadRequest)
+		return
+	}
+
+	message, verdict, ok := app.checkContent(w, r, user, id, newNote.Message)
+	if !ok {
+		return
+	}
+	// A note takes space of its owner, whoever edits it, and anonymous notes have none
+	var owner database.User
+	if note.UserID == user.ID {
+		owner = user
+	} else if note.UserID != "" {
+		owner, err = app.DB.GetUserByID(r.Context(), note.UserID)
+		if err != nil {
+			log.Printf("Failed to fetch owner of a note with the ID %q: %s", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	if !app.checkQuota(w, r, owner, false, int64(len(message)-len(note.Message))) {
+		return
+	}
+
 	updatedNote, err := app.DB.UpdateNote(r.Context(), database.UpdateNoteParams{
-		ID:      id,
-		Author:  newNote.Author,
-		Message: newNote.Message,
+		ID:         id,
+		Author:     newNote.Author,
+		Message:    message,
+		Visibility: newNote.Visibility,
+		Format:     newNote.Format,
 	})
 	if err != nil {
 		log.Printf("Failed to update a note with the ID %q: %s", id, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+
+	// Replies follow the visibility of the note
+	if updatedNote.Visibility != note.Visibility {
+		if err := app.setThreadVisibility(r.Context(), id, updatedNote.Visibility); err != nil {
+			log.Printf("Failed to change visibility of replies to a note with the ID %q: %s", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if moved {
+		err = app.DB.MoveNote(r.Context(), database.MoveNoteParams{
+			NotebookID: newNote.NotebookID,
+			ID:         id,
+			UserID:     user.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to move a note with the ID %q: %s", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		updatedNote.NotebookID = newNote.NotebookID
+	}
+
+	// Edits of guests are held for moderation like their notes, even once a moderator approved the note
+	flagged := true
+	switch {
+	case verdict.Action == filter.Flag:
+		err = app.flagFilteredNote(r.Context(), id, verdict)
+	case note.UserID == "" && app.anonymousPosting == anonymousModerate:
+		err = app.flagNote(r.Context(), id, "other", "Edit of an anonymous note held for moderation")
+	default:
+		flagged = false
+	}
+	if err != nil {
+		log.Printf("Failed to flag a note with the ID %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if flagged {
+		updatedNote.Hidden = 1
+	}
+	app.updateMentions(r.Context(), updatedNote, user)
 	log.Printf("Update a note with the ID %q", id)
 
 	payload, err := json.Marshal(&updatedNote)
@@ -326,6 +646,9 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.requireProofOfWork(w, r) {
+		return
+	}
 
 	var user database.User
 
@@ -358,9 +681,9 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if len(user.Password) < 4 {
-		log.Print("Provided password is too short")
-		http.Error(w, "Password must contain at least 4 characters", http.StatusBadRequest)
+	if err := app.passwordPolicy.Validate(user.Password); err != nil {
+		log.Printf("Provided password doesn't satisfy the policy: %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -419,7 +742,25 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	log.Printf("New user created with the ID %q", user.ID)
-	auth.RespondWithJWT(w, newUser.ID, newUser.Email)
+	app.respondWithSession(w, r, newUser.ID, newUser.Email)
+}
+
+func (app *application) getCurrentUserHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, &struct {
+		ID        string `json:"id"`
+		Email     string `json:"email"`
+		Name      string `json:"name"`
+		Username  string `json:"username"`
+		Verified  int64  `json:"verified"`
+		CreatedAt string `json:"created_at"`
+	}{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Username:  user.Username,
+		Verified:  user.Verified,
+		CreatedAt: user.CreatedAt,
+	})
 }
 
 func (app *application) authenticateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -445,25 +786,115 @@ func (app *application) authenticateUserHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Refuse to check the password while the account or the client is backing off
+	email := strings.ToLower(user.Email)
+	ip := clientIP(r)
+	if !app.beginLoginAttempt(w, r, email) {
+		return
+	}
+	defer app.endLoginAttempt(email, ip)
+
 	storedUser, err := app.DB.GetUserByEmail(r.Context(), user.Email)
-	if err != nil {
-		// Send 404 if user doesn't exist
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "User does not exist", http.StatusNotFound)
-			return
-		}
-		// Send 500 for any other errors
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		log.Printf("User authentication fail: %s", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	// Check password against the hash
-	if !auth.CheckPassword(storedUser.Password, user.Password) {
-		log.Print("Attempt to login with incorrect password")
-		http.Error(w, "Incorrect password", http.StatusUnauthorized)
+	// Check password against the hash. A missing user and a wrong password
+	// get the same response so that accounts can't be enumerated.
+	var valid bool
+	if errors.Is(err, sql.ErrNoRows) {
+		valid = auth.CheckDummyPassword(user.Password)
+	} else {
+		valid = auth.CheckPassword(storedUser.Password, user.Password)
+	}
+	if !valid {
+		log.Printf("Failed login attempt for %q from %s", email, ip)
+		app.recordFailedLogin(r.Context(), email, ip)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// Upgrade the stored hash if it was made with an outdated algorithm or parameters
+	if auth.NeedsRehash(storedUser.Password) {
+		app.rehashPassword(r.Context(), storedUser.ID, user.Password)
+	}
+
+	// Failed attempts are counted until the second factor is verified
+	if app.requireSecondFactor(w, r, storedUser) {
+		return
+	}
+
+	app.accountThrottle.Reset(email)
+	app.respondWithSession(w, r, storedUser.ID, storedUser.Email)
+}
+
+// beginLoginAttempt reserves a login attempt of the account and the client IP, or responds
+// with 429 and returns false while either is backing off after failed login attempts.
+// The attempt is reserved before the credentials are checked, so that concurrent guesses
+// can't all get past the throttle, and has to be ended with endLoginAttempt.
+func (app *application) beginLoginAttempt(w http.ResponseWriter, r *http.Request, email string) bool {
+	ip := clientIP(r)
+	wait := app.accountThrottle.Begin(email)
+	if wait <= 0 {
+		if wait = app.ipThrottle.Begin(ip); wait <= 0 {
+			return true
+		}
+		app.accountThrottle.Done(email)
+	}
+	log.Printf("Login attempt for %q from %s while throttled", email, ip)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+	return false
+}
+
+// endLoginAttempt ends a login attempt reserved by beginLoginAttempt.
+func (app *application) endLoginAttempt(email, ip string) {
+	app.accountThrottle.Done(email)
+	app.ipThrottle.Done(ip)
+}
+
+// rehashPassword replaces the stored password hash of the user with one made by the current parameters.
+func (app *application) rehashPassword(ctx context.Context, userID, password string) {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password: %s", err)
+		return
+	}
+	err = app.DB.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		Password: hashedPassword,
+		ID:       userID,
+	})
+	if err != nil {
+		log.Printf("Failed to store rehashed password of user %q: %s", userID, err)
 		return
 	}
+	log.Printf("Rehashed password of user %q", userID)
+}
 
-	auth.RespondWithJWT(w, storedUser.ID, storedUser.Email)
+// recordFailedLogin counts a failed login against the account and the client IP
+// and keeps an audit record of any lockout it causes.
+func (app *application) recordFailedLogin(ctx context.Context, email, ip string) {
+	lockouts := map[string]*auth.Lockout{
+		"account": app.accountThrottle.Fail(email),
+		"ip":      app.ipThrottle.Fail(ip),
+	}
+	for scope, lockout := range lockouts {
+		if lockout == nil {
+			continue
+		}
+		log.Printf("Locked out %s %q until %s after %d failed login attempts", scope, lockout.Key, lockout.Until.Format(time.RFC3339), lockout.Failures)
+		_, err := app.DB.CreateLockout(ctx, database.CreateLockoutParams{
+			ID:          utils.GenerateUniqueId(),
+			Scope:       scope,
+			Subject:     lockout.Key,
+			IpAddress:   ip,
+			Failures:    int64(lockout.Failures),
+			LockedUntil: lockout.Until.UTC().Format(timestampFormat),
+		})
+		if err != nil {
+			log.Printf("Failed to record a lockout: %s", err)
+		}
+	}
 }
@@ -5,11 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
 	"strings"
+	"time"
 )
 
+// timestampFormat matches the format of timestamps generated by the database.
+const timestampFormat = "2006-01-02 15:04:05.000Z"
+
 type malformedRequest struct {
 	status int
 	msg    string
@@ -110,3 +118,90 @@ func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 
 	return nil
 }
+
+// trustedProxies are reverse proxies whose X-Forwarded-For header is believed.
+var trustedProxies []netip.Prefix
+
+// loadTrustedProxies reads comma-separated addresses or CIDR ranges from TRUSTED_PROXIES.
+func loadTrustedProxies() {
+	for _, s := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				log.Fatalf("Invalid trusted proxy %q", s)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP address of the client that sent the request. Requests from trusted
+// proxies are traced back through X-Forwarded-For to the first address that isn't a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	// Proxies append the address they received the request from, so walk the list from the end
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if _, err := netip.ParseAddr(ip); err != nil {
+			break
+		}
+		host = ip
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return host
+}
+
+// respondWithJSON marshals payload and writes it with the given status code.
+func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal response: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// parseTimestamp parses a timestamp in the format generated by the database.
+func parseTimestamp(ts string) (time.Time, error) {
+	return time.Parse(timestampFormat, ts)
+}
+
+// expired returns true if the timestamp is in the past or can't be parsed.
+func expired(ts string) bool {
+	t, err := parseTimestamp(ts)
+	return err != nil || time.Now().After(t)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/ratelimit"
+	"github.com/chtozamm/annynotes-go/internal/render"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestApp returns an application with a fresh database and the default settings.
+func newTestApp(t *testing.T) *application {
+	t.Helper()
+	conn, err := dbConnect(filepath.Join(t.TempDir(), "annynotes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	app := &application{
+		DB:                  database.New(conn),
+		conn:                conn,
+		accountThrottle:     auth.NewLoginThrottle(3, 10, 15*time.Minute),
+		ipThrottle:          auth.NewLoginThrottle(20, 50, 15*time.Minute),
+		linkThrottle:        auth.NewLoginThrottle(3, 10, 15*time.Minute),
+		linkIPThrottle:      auth.NewLoginThrottle(20, 50, 15*time.Minute),
+		passwordPolicy:      auth.DefaultPasswordPolicy,
+		reportHideThreshold: 3,
+		quotas:              loadQuotas(),
+		editTokenTTL:        30 * time.Minute,
+		renderer:            render.New(100),
+		rateLimitStore:      ratelimit.NewMemoryStore(),
+	}
+	app.loadContentFilters()
+	return app
+}
+
+// createTestUser creates a user without a password.
+func createTestUser(t *testing.T, app *application, username, email string) database.User {
+	t.Helper()
+	user, err := app.DB.CreateUser(context.Background(), database.CreateUserParams{
+		ID:       utils.GenerateUniqueId(),
+		Email:    email,
+		Name:     username,
+		Username: username,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// resolveTestReport applies the moderator action to the only open report.
+func resolveTestReport(t *testing.T, app *application, moderator database.User, action string) {
+	t.Helper()
+	reports, err := app.DB.FetchReports(context.Background(), database.FetchReportsParams{Status: reportOpen, Limit: 2})
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("want one open report, got %d: %v", len(reports), err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/moderation/reports/"+reports[0].ID, strings.NewReader(`{"action":"`+action+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", reports[0].ID)
+	w := httptest.NewRecorder()
+	app.resolveReportHandler(w, r, moderator)
+	if w.Code >= 300 {
+		t.Fatalf("resolving the report responded with %d: %s", w.Code, w.Body)
+	}
+}
+
+// createTestNote creates a public note of the user. A reply is created if parentID is set.
+func createTestNote(t *testing.T, app *application, user database.User, message, parentID string) database.Note {
+	t.Helper()
+	note, err := app.DB.CreateNote(context.Background(), database.CreateNoteParams{
+		ID:         utils.GenerateUniqueId(),
+		Author:     user.Username,
+		Message:    message,
+		UserID:     user.ID,
+		Visibility: visibilityPublic,
+		ParentID:   parentID,
+		Format:     formatPlain,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return note
+}
@@ -15,16 +15,18 @@ var jwtKey = []byte(os.Getenv("AUTH_SECRET_KEY"))
 var tokens []string
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"session_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(id, email string) (string, error) {
+func GenerateJWT(id, email, sessionID string) (string, error) {
 	expirationTime := time.Now().Add(5 * time.Minute)
 	claims := &Claims{
-		UserID: id,
-		Email:  email,
+		UserID:    id,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -45,15 +47,63 @@ func ValidateJWT(headers http.Header) (*Claims, error) {
 	}
 	reqToken := authHeader[1]
 
+	return validateAccessToken(reqToken)
+}
+
+// validateAccessToken validates JWT issued by GenerateJWT and returns its claims.
+func validateAccessToken(reqToken string) (*Claims, error) {
+	claims, err := parseToken(reqToken)
+	if err != nil {
+		return nil, err
+	}
+	// Tokens issued for a specific purpose are not access tokens
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("token is not an access token")
+	}
+
+	return claims, nil
+}
+
+// challengeAudience marks tokens that only prove the password was correct
+// and have to be exchanged for an access token with a second factor.
+const challengeAudience = "2fa-challenge"
+
+// GenerateChallengeToken returns a short-lived token for the second step of the login.
+func GenerateChallengeToken(id, email string) (string, error) {
+	expirationTime := time.Now().Add(5 * time.Minute)
+	claims := &Claims{
+		UserID: id,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
+// ValidateChallengeToken validates a token issued by GenerateChallengeToken and returns its claims.
+func ValidateChallengeToken(reqToken string) (*Claims, error) {
+	claims, err := parseToken(reqToken, jwt.WithAudience(challengeAudience))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func parseToken(reqToken string, options ...jwt.ParserOption) (*Claims, error) {
 	claims := &Claims{}
 
 	tkn, err := jwt.ParseWithClaims(reqToken, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, options...)
 	if err != nil {
 		switch {
 		case errors.Is(err, jwt.ErrTokenExpired):
 			return nil, errors.New("token is expired")
+		case errors.Is(err, jwt.ErrTokenInvalidAudience):
+			return nil, errors.New("token was issued for another purpose")
 		case errors.Is(err, jwt.ErrTokenInvalidClaims):
 			return nil, errors.New("malformed token: token contains invalid claims")
 		case errors.Is(err, jwt.ErrTokenMalformed):
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// SessionCookieName is the HttpOnly cookie holding the access token in cookie mode
+	SessionCookieName = "annynotes_session"
+	// CSRFCookieName is readable by scripts, so that the frontend can echo it in CSRFHeaderName
+	CSRFCookieName = "annynotes_csrf"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// ValidateRequest validates the access token from either the "Authorization" header
+// or the session cookie and returns its claims. fromCookie reports whether the token
+// came from the cookie, in which case state-changing requests must pass CheckCSRF.
+func ValidateRequest(r *http.Request) (claims *Claims, fromCookie bool, err error) {
+	if r.Header.Get("Authorization") != "" {
+		claims, err := ValidateJWT(r.Header)
+		return claims, false, err
+	}
+
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		// Report the missing header, as it is the primary way to authenticate
+		claims, err := ValidateJWT(r.Header)
+		return claims, false, err
+	}
+
+	claims, err = validateAccessToken(cookie.Value)
+	return claims, true, err
+}
+
+// CSRFToken returns the CSRF token of the session. The token is derived from the session ID,
+// so that it doesn't have to be stored and can't be planted by another site.
+func CSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckCSRF returns true if the request is safe or carries the CSRF token of the session.
+func CheckCSRF(r *http.Request, sessionID string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	token := r.Header.Get(CSRFHeaderName)
+	return token != "" && hmac.Equal([]byte(token), []byte(CSRFToken(sessionID)))
+}
+
+// RespondWithCookie sets the access token in an HttpOnly cookie and responds with the CSRF token
+// instead of the access token.
+func RespondWithCookie(w http.ResponseWriter, userID, userEmail, sessionID string) {
+
+	token, err := GenerateJWT(userID, userEmail, sessionID)
+	if err != nil {
+		log.Printf("Failed to generate JWT: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	csrfToken := CSRFToken(sessionID)
+	expires := time.Now().Add(5 * time.Minute)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	payload, err := json.Marshal(&struct {
+		CSRFToken string `json:"csrf_token"`
+	}{CSRFToken: csrfToken})
+	if err != nil {
+		log.Printf("Failed to marshal CSRF token: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(payload)
+}
+
+// ClearCookies removes the session and CSRF cookies from the browser.
+func ClearCookies(w http.ResponseWriter) {
+	for _, name := range []string{SessionCookieName, CSRFCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: name == SessionCookieName,
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
+// OIDCStateCookieName is the cookie that carries OIDCState through the redirect to an OpenID provider.
+const OIDCStateCookieName = "annynotes_oidc"
+
+// oidcStateAudience marks tokens that carry OIDCState
+const oidcStateAudience = "oidc-state"
+
+// OIDCState is what the callback of an OpenID Connect login needs to know about the login it completes.
+type OIDCState struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	CookieMode   bool   `json:"cookie_mode"`
+	// LinkUserID is the signed in user who links the identity to their account
+	LinkUserID string `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SetOIDCStateCookie signs the state and stores it in a short-lived cookie.
+// The cookie is SameSite=Lax, as it has to survive the top-level redirect back from the provider.
+func SetOIDCStateCookie(w http.ResponseWriter, state OIDCState) error {
+	expires := time.Now().Add(10 * time.Minute)
+	state.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expires),
+		Audience:  jwt.ClaimStrings{oidcStateAudience},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &state).SignedString(jwtKey)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// OIDCStateFromCookie returns the state stored by SetOIDCStateCookie and removes the cookie.
+func OIDCStateFromCookie(w http.ResponseWriter, r *http.Request) (*OIDCState, error) {
+	cookie, err := r.Cookie(OIDCStateCookieName)
+	if err != nil {
+		return nil, errors.New("login state is missing")
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	state := &OIDCState{}
+	_, err = jwt.ParseWithClaims(cookie.Value, state, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(oidcStateAudience))
+	if err != nil {
+		return nil, errors.New("login state is invalid or expired")
+	}
+	return state, nil
+}
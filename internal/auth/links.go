@@ -0,0 +1,19 @@
+package auth
+
+// shareLinkPrefix tells share link tokens apart from other opaque tokens
+const shareLinkPrefix = "ansl_"
+
+// GenerateShareLinkToken returns a new token for a share link.
+// Only its hash is stored, like with OAuth tokens.
+func GenerateShareLinkToken() (string, error) {
+	return randomToken(shareLinkPrefix)
+}
+
+// editTokenPrefix tells edit tokens of anonymous notes apart from other opaque tokens
+const editTokenPrefix = "anet_"
+
+// GenerateEditToken returns a new token for editing an anonymous note.
+// Only its hash is stored, like with share links.
+func GenerateEditToken() (string, error) {
+	return randomToken(editTokenPrefix)
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashParams are the argon2id parameters used for new password hashes.
+type HashParams struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultHashParams follow the second recommended option of RFC 9106.
+var DefaultHashParams = HashParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var hashParams = DefaultHashParams
+
+// dummyHash is checked against when the user doesn't exist,
+// so that a failed login takes about as long as a wrong password.
+var dummyHash, _ = HashPassword("annynotes-dummy-password")
+
+// SetHashParams changes the parameters used for new password hashes.
+// Stored hashes made with other parameters are reported by NeedsRehash.
+func SetHashParams(params HashParams) {
+	hashParams = params
+	dummyHash, _ = HashPassword("annynotes-dummy-password")
+}
+
+// HashPassword hashes password with argon2id and encodes it in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	p := hashParams
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword returns true if hashed and provided passwords match.
+// Both argon2id and legacy bcrypt hashes are supported.
+func CheckPassword(hashedPassword, currPassword string) bool {
+	if !strings.HasPrefix(hashedPassword, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(currPassword))
+		return err == nil
+	}
+
+	p, salt, key, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return false
+	}
+	currKey := argon2.IDKey([]byte(currPassword), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, currKey) == 1
+}
+
+// CheckDummyPassword spends the same time as CheckPassword and always returns false.
+func CheckDummyPassword(currPassword string) bool {
+	CheckPassword(dummyHash, currPassword)
+	return false
+}
+
+// NeedsRehash returns true if the hash wasn't made with argon2id and the current parameters.
+func NeedsRehash(hashedPassword string) bool {
+	p, salt, _, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return p.Memory != hashParams.Memory ||
+		p.Iterations != hashParams.Iterations ||
+		p.Parallelism != hashParams.Parallelism ||
+		p.KeyLength != hashParams.KeyLength ||
+		uint32(len(salt)) != hashParams.SaltLength
+}
+
+// decodeArgon2Hash parses a hash encoded by HashPassword.
+func decodeArgon2Hash(hashedPassword string) (HashParams, []byte, []byte, error) {
+	var p HashParams
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, err
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = func() map[string]struct{} {
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return passwords
+}()
+
+// PasswordPolicy describes which passwords are acceptable for new accounts.
+type PasswordPolicy struct {
+	MinLength    int
+	MaxLength    int
+	RejectCommon bool
+}
+
+// DefaultPasswordPolicy is used unless configured otherwise.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	MaxLength:    128,
+	RejectCommon: true,
+}
+
+// Validate returns an error describing why the password doesn't satisfy the policy.
+// The error message is meant to be shown to the user.
+func (p PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	switch {
+	case length < p.MinLength:
+		return fmt.Errorf("Password must contain at least %d characters", p.MinLength)
+	case p.MaxLength > 0 && length > p.MaxLength:
+		return fmt.Errorf("Password must contain at most %d characters", p.MaxLength)
+	}
+	if p.RejectCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			return errors.New("Password is too common, choose another one")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginThrottle keeps track of failed login attempts per key (an account or a client IP)
+// and tells how long the key has to wait before it may try again.
+//
+// The first FreeAttempts failures are not delayed, every following failure doubles
+// the delay starting from BaseDelay, and after MaxAttempts failures the key is locked
+// out for LockoutDuration. Failures older than Window are forgotten.
+//
+// Attempts are reserved with Begin before the credentials are checked and count as failures
+// until they end, so that concurrent attempts can't all get past the throttle.
+type LoginThrottle struct {
+	FreeAttempts    int
+	MaxAttempts     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+type loginAttempts struct {
+	failures int
+	// inFlight is the number of attempts that began and haven't ended yet
+	inFlight    int
+	lastFailure time.Time
+	retryAt     time.Time
+	lockedUntil time.Time
+}
+
+// Lockout describes a key that has just been locked out.
+type Lockout struct {
+	Key      string
+	Failures int
+	Until    time.Time
+}
+
+// NewLoginThrottle returns a throttle that starts delaying a key after freeAttempts failures
+// and locks it out for lockoutDuration after maxAttempts failures.
+func NewLoginThrottle(freeAttempts, maxAttempts int, lockoutDuration time.Duration) *LoginThrottle {
+	return &LoginThrottle{
+		FreeAttempts:    freeAttempts,
+		MaxAttempts:     maxAttempts,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutDuration: lockoutDuration,
+		Window:          time.Hour,
+		attempts:        make(map[string]*loginAttempts),
+	}
+}
+
+// Wait returns how long the key has to wait before the next login attempt is allowed.
+// Zero means the attempt may proceed.
+func (t *LoginThrottle) Wait(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	a := t.get(key, now)
+	if a == nil {
+		return 0
+	}
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	if now.Before(a.retryAt) {
+		return a.retryAt.Sub(now)
+	}
+	return 0
+}
+
+// Begin reserves a login attempt for the key and returns zero, or returns how long the key has
+// to wait if the attempt isn't allowed. Attempts that began are counted as failures until they
+// end with Done, which has to be called whether the attempt failed or not.
+func (t *LoginThrottle) Begin(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	a := t.get(key, now)
+	if a == nil {
+		t.prune(now)
+		a = &loginAttempts{}
+		t.attempts[key] = a
+	}
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	if now.Before(a.retryAt) {
+		return a.retryAt.Sub(now)
+	}
+	// Had the attempts in flight failed, this one would have to wait
+	if pending := a.failures + a.inFlight; a.inFlight > 0 && (pending > t.FreeAttempts || pending >= t.MaxAttempts) {
+		return t.delay(pending)
+	}
+	a.inFlight++
+	return 0
+}
+
+// Done ends an attempt reserved by Begin. Failures are recorded with Fail before the attempt ends.
+func (t *LoginThrottle) Done(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if a, ok := t.attempts[key]; ok && a.inFlight > 0 {
+		a.inFlight--
+	}
+}
+
+// Fail records a failed login attempt for the key. It returns a non-nil Lockout
+// when this failure caused the key to be locked out.
+func (t *LoginThrottle) Fail(key string) *Lockout {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	a := t.get(key, now)
+	if a == nil {
+		t.prune(now)
+		a = &loginAttempts{}
+		t.attempts[key] = a
+	}
+	a.failures++
+	a.lastFailure = now
+
+	if a.failures >= t.MaxAttempts {
+		a.lockedUntil = now.Add(t.LockoutDuration)
+		lockout := &Lockout{Key: key, Failures: a.failures, Until: a.lockedUntil}
+		// Start over once the lockout expires
+		a.failures = 0
+		a.retryAt = time.Time{}
+		return lockout
+	}
+
+	if a.failures > t.FreeAttempts {
+		a.retryAt = now.Add(t.delay(a.failures))
+	}
+	return nil
+}
+
+// delay returns how long a key has to wait for the next attempt after the number of failures.
+func (t *LoginThrottle) delay(failures int) time.Duration {
+	if failures <= t.FreeAttempts {
+		return t.BaseDelay
+	}
+	delay := t.BaseDelay << (failures - t.FreeAttempts - 1)
+	if delay <= 0 || delay > t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	return delay
+}
+
+// Reset forgets all failed attempts of the key, e.g. after a successful login,
+// along with the attempts in flight.
+func (t *LoginThrottle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.attempts, key)
+}
+
+// get returns attempts of the key, dropping them if they have expired.
+func (t *LoginThrottle) get(key string, now time.Time) *loginAttempts {
+	a, ok := t.attempts[key]
+	if !ok {
+		return nil
+	}
+	if t.expired(a, now) {
+		delete(t.attempts, key)
+		return nil
+	}
+	return a
+}
+
+func (t *LoginThrottle) expired(a *loginAttempts, now time.Time) bool {
+	return a.inFlight == 0 && now.After(a.lockedUntil) && now.Sub(a.lastFailure) > t.Window
+}
+
+// prune removes expired entries so that the map doesn't grow without bound.
+func (t *LoginThrottle) prune(now time.Time) {
+	for key, a := range t.attempts {
+		if t.expired(a, now) {
+			delete(t.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleBacksOff(t *testing.T) {
+	throttle := NewLoginThrottle(2, 10, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		throttle.Fail("ann")
+		if wait := throttle.Wait("ann"); wait != 0 {
+			t.Fatalf("free failure %d delayed the next attempt by %s", i+1, wait)
+		}
+	}
+
+	tests := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for _, want := range tests {
+		throttle.Fail("ann")
+		if wait := throttle.Wait("ann"); wait > want || wait < want-time.Second/2 {
+			t.Errorf("wait = %s, want %s", wait, want)
+		}
+	}
+
+	if wait := throttle.Wait("bob"); wait != 0 {
+		t.Errorf("failures of one key delayed another by %s", wait)
+	}
+	throttle.Reset("ann")
+	if wait := throttle.Wait("ann"); wait != 0 {
+		t.Errorf("wait after reset = %s, want 0", wait)
+	}
+}
+
+func TestLoginThrottleCapsDelay(t *testing.T) {
+	throttle := NewLoginThrottle(0, 100, time.Minute)
+	for i := 0; i < 80; i++ {
+		throttle.Fail("ann")
+	}
+	if wait := throttle.Wait("ann"); wait > throttle.MaxDelay || wait <= 0 {
+		t.Errorf("wait = %s, want at most %s", wait, throttle.MaxDelay)
+	}
+}
+
+func TestLoginThrottleLocksOut(t *testing.T) {
+	throttle := NewLoginThrottle(3, 5, 15*time.Minute)
+	for i := 1; i < 5; i++ {
+		if lockout := throttle.Fail("ann"); lockout != nil {
+			t.Fatalf("failure %d locked the key out", i)
+		}
+	}
+
+	lockout := throttle.Fail("ann")
+	if lockout == nil || lockout.Key != "ann" || lockout.Failures != 5 {
+		t.Fatalf("lockout = %+v, want one of ann after 5 failures", lockout)
+	}
+	if wait := throttle.Begin("ann"); wait < 14*time.Minute {
+		t.Errorf("wait while locked out = %s, want the lockout duration", wait)
+	}
+}
+
+func TestLoginThrottleBeginCountsAttemptsInFlight(t *testing.T) {
+	throttle := NewLoginThrottle(2, 10, time.Minute)
+
+	// Free attempts may run at the same time
+	for i := 0; i < 3; i++ {
+		if wait := throttle.Begin("ann"); wait != 0 {
+			t.Fatalf("attempt %d had to wait %s", i+1, wait)
+		}
+	}
+	if wait := throttle.Begin("ann"); wait != time.Second {
+		t.Errorf("attempt past the free ones while others are in flight: wait = %s, want 1s", wait)
+	}
+
+	// Attempts that succeed don't count as failures
+	for i := 0; i < 3; i++ {
+		throttle.Done("ann")
+	}
+	if wait := throttle.Begin("ann"); wait != 0 {
+		t.Errorf("attempt after the others ended had to wait %s", wait)
+	}
+	throttle.Done("ann")
+	throttle.Done("ann")
+	if wait := throttle.Begin("ann"); wait != 0 {
+		t.Errorf("extra Done let the count go below zero: wait = %s", wait)
+	}
+}
+
+func TestLoginThrottleBeginIsAtomic(t *testing.T) {
+	throttle := NewLoginThrottle(3, 10, time.Minute)
+	start := make(chan struct{})
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if throttle.Begin("ann") != 0 {
+				return
+			}
+			allowed.Add(1)
+			// The password check is slow and fails
+			time.Sleep(10 * time.Millisecond)
+			throttle.Fail("ann")
+			throttle.Done("ann")
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// As many attempts as if they ran one after another without waiting
+	if n := allowed.Load(); n != 4 {
+		t.Errorf("%d concurrent attempts got past the throttle, want 4", n)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the test vectors in RFC 6238, appendix B.
+const rfc6238Secret = "12345678901234567890"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes, of which 6-digit codes are the last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		want := tt.want[len(tt.want)-totpDigits:]
+		if got := totpCode([]byte(rfc6238Secret), tt.unix/totpPeriod); got != want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32NoPadding.EncodeToString([]byte(rfc6238Secret))
+	at := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		at     time.Time
+		valid  bool
+	}{
+		{"current step", secret, "050471", at, true},
+		{"lowercase secret", strings.ToLower(secret), "050471", at, true},
+		{"previous step", secret, "050471", at.Add(totpPeriod * time.Second), true},
+		{"next step", secret, "050471", at.Add(-totpPeriod * time.Second), true},
+		{"outside the skew", secret, "050471", at.Add(2 * totpPeriod * time.Second), false},
+		{"wrong code", secret, "050472", at, false},
+		{"8 digits", secret, "14050471", at, false},
+		{"invalid secret", "not base32!", "050471", at, false},
+	}
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(tt.secret, tt.code, tt.at)
+		if ok != tt.valid {
+			t.Errorf("%s: valid = %t, want %t", tt.name, ok, tt.valid)
+		}
+		if ok && step != 1111111111/totpPeriod {
+			t.Errorf("%s: step = %d, want %d", tt.name, step, 1111111111/totpPeriod)
+		}
+	}
+}
@@ -4,14 +4,228 @@
 
 package database
 
-type Note struct {
+import (
+	"database/sql"
+)
+
+type Favorite struct {
+	UserID    string `json:"user_id"`
+	NoteID    string `json:"note_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type Follow struct {
+	FollowerID string `json:"follower_id"`
+	FolloweeID string `json:"followee_id"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type Identity struct {
 	ID        string `json:"id"`
-	Author    string `json:"author"`
-	Message   string `json:"message"`
-	UpdatedAt string `json:"updated_at"`
+	UserID    string `json:"user_id"`
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
 	CreatedAt string `json:"created_at"`
+}
+
+type Lockout struct {
+	ID          string `json:"id"`
+	Scope       string `json:"scope"`
+	Subject     string `json:"subject"`
+	IpAddress   string `json:"ip_address"`
+	Failures    int64  `json:"failures"`
+	LockedUntil string `json:"locked_until"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type Mention struct {
+	NoteID    string `json:"note_id"`
 	UserID    string `json:"user_id"`
-	Verified  int64  `json:"verified"`
+	CreatedAt string `json:"created_at"`
+}
+
+type Note struct {
+	ID         string `json:"id"`
+	Author     string `json:"author"`
+	Message    string `json:"message"`
+	UpdatedAt  string `json:"updated_at"`
+	CreatedAt  string `json:"created_at"`
+	UserID     string `json:"user_id"`
+	Verified   int64  `json:"verified"`
+	Visibility string `json:"visibility"`
+	NotebookID string `json:"notebook_id"`
+	Pinned     int64  `json:"pinned"`
+	ParentID   string `json:"parent_id"`
+	Deleted    int64  `json:"deleted"`
+	Hidden     int64  `json:"hidden"`
+	Format     string `json:"format"`
+	Revision   int64  `json:"revision"`
+}
+
+type NoteCollaborator struct {
+	NoteID    string `json:"note_id"`
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type NoteCreation struct {
+	UserID    string `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type NoteEditToken struct {
+	NoteID    string `json:"note_id"`
+	TokenHash string `json:"token_hash"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type NoteReaction struct {
+	NoteID    string `json:"note_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	CreatedAt string `json:"created_at"`
+}
+
+type Notebook struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int64  `json:"position"`
+	UpdatedAt   string `json:"updated_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type Notification struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	ActorID   string `json:"actor_id"`
+	NoteID    string `json:"note_id"`
+	Emoji     string `json:"emoji"`
+	Read      int64  `json:"read"`
+	CreatedAt string `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	SecretHash   string `json:"secret_hash"`
+	RedirectUris string `json:"redirect_uris"`
+	UserID       string `json:"user_id"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type OauthCode struct {
+	CodeHash      string `json:"code_hash"`
+	ClientID      string `json:"client_id"`
+	UserID        string `json:"user_id"`
+	RedirectUri   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+	ExpiresAt     string `json:"expires_at"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type OauthToken struct {
+	ID        string         `json:"id"`
+	TokenHash string         `json:"token_hash"`
+	Kind      string         `json:"kind"`
+	GrantID   string         `json:"grant_id"`
+	ClientID  string         `json:"client_id"`
+	UserID    string         `json:"user_id"`
+	Scope     string         `json:"scope"`
+	ExpiresAt string         `json:"expires_at"`
+	RevokedAt sql.NullString `json:"revoked_at"`
+	CreatedAt string         `json:"created_at"`
+}
+
+type Passkey struct {
+	ID           string         `json:"id"`
+	UserID       string         `json:"user_id"`
+	CredentialID string         `json:"credential_id"`
+	PublicKey    []byte         `json:"public_key"`
+	SignCount    int64          `json:"sign_count"`
+	Name         string         `json:"name"`
+	CreatedAt    string         `json:"created_at"`
+	LastUsedAt   sql.NullString `json:"last_used_at"`
+}
+
+type PowChallenge struct {
+	Challenge string `json:"challenge"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type RateLimit struct {
+	Key       string  `json:"key"`
+	Tokens    float64 `json:"tokens"`
+	Allowed   int64   `json:"allowed"`
+	UpdatedAt float64 `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	CodeHash  string         `json:"code_hash"`
+	UsedAt    sql.NullString `json:"used_at"`
+	CreatedAt string         `json:"created_at"`
+}
+
+type Report struct {
+	ID            string `json:"id"`
+	NoteID        string `json:"note_id"`
+	ReporterID    string `json:"reporter_id"`
+	Reason        string `json:"reason"`
+	Details       string `json:"details"`
+	Status        string `json:"status"`
+	Action        string `json:"action"`
+	ModeratorID   string `json:"moderator_id"`
+	ModeratorNote string `json:"moderator_note"`
+	ResolvedAt    string `json:"resolved_at"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type Session struct {
+	ID         string         `json:"id"`
+	UserID     string         `json:"user_id"`
+	UserAgent  string         `json:"user_agent"`
+	IpAddress  string         `json:"ip_address"`
+	CreatedAt  string         `json:"created_at"`
+	LastSeenAt string         `json:"last_seen_at"`
+	RevokedAt  sql.NullString `json:"revoked_at"`
+}
+
+type ShareLink struct {
+	ID        string         `json:"id"`
+	NoteID    string         `json:"note_id"`
+	TokenHash string         `json:"token_hash"`
+	Password  sql.NullString `json:"password"`
+	MaxViews  sql.NullInt64  `json:"max_views"`
+	Views     int64          `json:"views"`
+	ExpiresAt sql.NullString `json:"expires_at"`
+	CreatedAt string         `json:"created_at"`
+	RevokedAt sql.NullString `json:"revoked_at"`
+}
+
+type SpamDocument struct {
+	Class     string `json:"class"`
+	Documents int64  `json:"documents"`
+}
+
+type SpamToken struct {
+	Token string `json:"token"`
+	Spam  int64  `json:"spam"`
+	Ham   int64  `json:"ham"`
+}
+
+type TwoFactor struct {
+	UserID       string `json:"user_id"`
+	Secret       string `json:"secret"`
+	Enabled      int64  `json:"enabled"`
+	LastUsedStep int64  `json:"last_used_step"`
+	CreatedAt    string `json:"created_at"`
 }
 
 type User struct {
@@ -23,4 +237,20 @@ type User struct {
 	UpdatedAt string `json:"updated_at"`
 	CreatedAt string `json:"created_at"`
 	Verified  int64  `json:"verified"`
+	Role      string `json:"role"`
+	Suspended int64  `json:"suspended"`
+}
+
+type UserRestriction struct {
+	UserID    string `json:"user_id"`
+	TargetID  string `json:"target_id"`
+	Kind      string `json:"kind"`
+	CreatedAt string `json:"created_at"`
+}
+
+type WebauthnChallenge struct {
+	Challenge string `json:"challenge"`
+	Ceremony  string `json:"ceremony"`
+	UserID    string `json:"user_id"`
+	ExpiresAt string `json:"expires_at"`
 }
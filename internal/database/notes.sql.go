@@ -9,18 +9,53 @@ import (
 	"context"
 )
 
+const countReplies = `-- name: CountReplies :one
+SELECT count(*) FROM notes
+WHERE parent_id = ?
+`
+
+func (q *Queries) CountReplies(ctx context.Context, parentID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countReplies, parentID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countVisibleReplies = `-- name: CountVisibleReplies :one
+SELECT count(*) FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+`
+
+type CountVisibleRepliesParams struct {
+	ParentID string `json:"parent_id"`
+	ViewerID string `json:"viewer_id"`
+}
+
+func (q *Queries) CountVisibleReplies(ctx context.Context, arg CountVisibleRepliesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisibleReplies, arg.ParentID, arg.ViewerID, arg.ViewerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createNote = `-- name: CreateNote :one
-INSERT INTO notes (id, author, message, user_id, verified) 
-VALUES (?, ?, ?, ?, ?) 
-RETURNING id, author, message, updated_at, created_at, user_id, verified
+INSERT INTO notes (id, author, message, user_id, verified, visibility, notebook_id, parent_id, format) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
+RETURNING id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision
 `
 
 type CreateNoteParams struct {
-	ID       string `json:"id"`
-	Author   string `json:"author"`
-	Message  string `json:"message"`
-	UserID   string `json:"user_id"`
-	Verified int64  `json:"verified"`
+	ID         string `json:"id"`
+	Author     string `json:"author"`
+	Message    string `json:"message"`
+	UserID     string `json:"user_id"`
+	Verified   int64  `json:"verified"`
+	Visibility string `json:"visibility"`
+	NotebookID string `json:"notebook_id"`
+	ParentID   string `json:"parent_id"`
+	Format     string `json:"format"`
 }
 
 func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, error) {
@@ -30,6 +65,10 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, e
 		arg.Message,
 		arg.UserID,
 		arg.Verified,
+		arg.Visibility,
+		arg.NotebookID,
+		arg.ParentID,
+		arg.Format,
 	)
 	var i Note
 	err := row.Scan(
@@ -40,6 +79,14 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, e
 		&i.CreatedAt,
 		&i.UserID,
 		&i.Verified,
+		&i.Visibility,
+		&i.NotebookID,
+		&i.Pinned,
+		&i.ParentID,
+		&i.Deleted,
+		&i.Hidden,
+		&i.Format,
+		&i.Revision,
 	)
 	return i, err
 }
@@ -53,8 +100,153 @@ func (q *Queries) DeleteNote(ctx context.Context, id string) error {
 	return err
 }
 
+const fetchAllReplies = `-- name: FetchAllReplies :many
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE parent_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) FetchAllReplies(ctx context.Context, parentID string) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchAllReplies, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Message,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchNotebookNotes = `-- name: FetchNotebookNotes :many
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE notebook_id = ? AND user_id = ? AND deleted = 0
+ORDER BY pinned DESC, created_at ASC
+`
+
+type FetchNotebookNotesParams struct {
+	NotebookID string `json:"notebook_id"`
+	UserID     string `json:"user_id"`
+}
+
+func (q *Queries) FetchNotebookNotes(ctx context.Context, arg FetchNotebookNotesParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotebookNotes, arg.NotebookID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Message,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchNotebookNotesDESC = `-- name: FetchNotebookNotesDESC :many
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE notebook_id = ? AND user_id = ? AND deleted = 0
+ORDER BY pinned DESC, created_at DESC
+`
+
+type FetchNotebookNotesDESCParams struct {
+	NotebookID string `json:"notebook_id"`
+	UserID     string `json:"user_id"`
+}
+
+func (q *Queries) FetchNotebookNotesDESC(ctx context.Context, arg FetchNotebookNotesDESCParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotebookNotesDESC, arg.NotebookID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Message,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const fetchNoteByID = `-- name: FetchNoteByID :one
-SELECT id, author, message, updated_at, created_at, user_id, verified FROM notes WHERE id = ?
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes WHERE id = ?
 `
 
 func (q *Queries) FetchNoteByID(ctx context.Context, id string) (Note, error) {
@@ -68,17 +260,28 @@ func (q *Queries) FetchNoteByID(ctx context.Context, id string) (Note, error) {
 		&i.CreatedAt,
 		&i.UserID,
 		&i.Verified,
+		&i.Visibility,
+		&i.NotebookID,
+		&i.Pinned,
+		&i.ParentID,
+		&i.Deleted,
+		&i.Hidden,
+		&i.Format,
+		&i.Revision,
 	)
 	return i, err
 }
 
 const fetchNotes = `-- name: FetchNotes :many
-SELECT id, author, message, updated_at, created_at, user_id, verified FROM notes
-ORDER BY created_at ASC
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE (visibility = 'public' OR (user_id = ? AND user_id != '')) AND parent_id = '' AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY pinned DESC, created_at ASC
 `
 
-func (q *Queries) FetchNotes(ctx context.Context) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, fetchNotes)
+func (q *Queries) FetchNotes(ctx context.Context, viewerID string) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotes, viewerID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +297,14 @@ func (q *Queries) FetchNotes(ctx context.Context) ([]Note, error) {
 			&i.CreatedAt,
 			&i.UserID,
 			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
 		); err != nil {
 			return nil, err
 		}
@@ -109,12 +320,15 @@ func (q *Queries) FetchNotes(ctx context.Context) ([]Note, error) {
 }
 
 const fetchNotesDESC = `-- name: FetchNotesDESC :many
-SELECT id, author, message, updated_at, created_at, user_id, verified FROM notes
-ORDER BY created_at DESC
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE (visibility = 'public' OR (user_id = ? AND user_id != '')) AND parent_id = '' AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY pinned DESC, created_at DESC
 `
 
-func (q *Queries) FetchNotesDESC(ctx context.Context) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, fetchNotesDESC)
+func (q *Queries) FetchNotesDESC(ctx context.Context, viewerID string) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotesDESC, viewerID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +344,14 @@ func (q *Queries) FetchNotesDESC(ctx context.Context) ([]Note, error) {
 			&i.CreatedAt,
 			&i.UserID,
 			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
 		); err != nil {
 			return nil, err
 		}
@@ -145,13 +367,25 @@ func (q *Queries) FetchNotesDESC(ctx context.Context) ([]Note, error) {
 }
 
 const fetchNotesFromAuthor = `-- name: FetchNotesFromAuthor :many
-SELECT id, author, message, updated_at, created_at, user_id, verified FROM notes
-WHERE author = ?
-ORDER BY created_at ASC
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE author = ? AND (visibility = 'public' OR (user_id = ? AND user_id != '')) AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY pinned DESC, created_at ASC
 `
 
-func (q *Queries) FetchNotesFromAuthor(ctx context.Context, author string) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, fetchNotesFromAuthor, author)
+type FetchNotesFromAuthorParams struct {
+	Author   string `json:"author"`
+	ViewerID string `json:"viewer_id"`
+}
+
+func (q *Queries) FetchNotesFromAuthor(ctx context.Context, arg FetchNotesFromAuthorParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotesFromAuthor,
+		arg.Author,
+		arg.ViewerID,
+		arg.ViewerID,
+		arg.ViewerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -167,6 +401,14 @@ func (q *Queries) FetchNotesFromAuthor(ctx context.Context, author string) ([]No
 			&i.CreatedAt,
 			&i.UserID,
 			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
 		); err != nil {
 			return nil, err
 		}
@@ -182,13 +424,121 @@ func (q *Queries) FetchNotesFromAuthor(ctx context.Context, author string) ([]No
 }
 
 const fetchNotesFromAuthorDESC = `-- name: FetchNotesFromAuthorDESC :many
-SELECT id, author, message, updated_at, created_at, user_id, verified FROM notes
-WHERE author = ?
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE author = ? AND (visibility = 'public' OR (user_id = ? AND user_id != '')) AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY pinned DESC, created_at DESC
+`
+
+type FetchNotesFromAuthorDESCParams struct {
+	Author   string `json:"author"`
+	ViewerID string `json:"viewer_id"`
+}
+
+func (q *Queries) FetchNotesFromAuthorDESC(ctx context.Context, arg FetchNotesFromAuthorDESCParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchNotesFromAuthorDESC,
+		arg.Author,
+		arg.ViewerID,
+		arg.ViewerID,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Message,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchRecentMessages = `-- name: FetchRecentMessages :many
+SELECT message FROM notes
+WHERE user_id = ? AND id != ? AND deleted = 0
 ORDER BY created_at DESC
+LIMIT 20
+`
+
+type FetchRecentMessagesParams struct {
+	UserID string `json:"user_id"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) FetchRecentMessages(ctx context.Context, arg FetchRecentMessagesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, fetchRecentMessages, arg.UserID, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, err
+		}
+		items = append(items, message)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchReplies = `-- name: FetchReplies :many
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY created_at ASC
+LIMIT ? OFFSET ?
 `
 
-func (q *Queries) FetchNotesFromAuthorDESC(ctx context.Context, author string) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, fetchNotesFromAuthorDESC, author)
+type FetchRepliesParams struct {
+	ParentID string `json:"parent_id"`
+	ViewerID string `json:"viewer_id"`
+	Limit    int64  `json:"limit"`
+	Offset   int64  `json:"offset"`
+}
+
+func (q *Queries) FetchReplies(ctx context.Context, arg FetchRepliesParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchReplies,
+		arg.ParentID,
+		arg.ViewerID,
+		arg.ViewerID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +554,14 @@ func (q *Queries) FetchNotesFromAuthorDESC(ctx context.Context, author string) (
 			&i.CreatedAt,
 			&i.UserID,
 			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
 		); err != nil {
 			return nil, err
 		}
@@ -218,20 +576,198 @@ func (q *Queries) FetchNotesFromAuthorDESC(ctx context.Context, author string) (
 	return items, nil
 }
 
+const fetchVisibleReplies = `-- name: FetchVisibleReplies :many
+SELECT id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = ?)
+  AND (hidden = 0 OR (user_id = ? AND user_id != ''))
+ORDER BY created_at ASC
+`
+
+type FetchVisibleRepliesParams struct {
+	ParentID string `json:"parent_id"`
+	ViewerID string `json:"viewer_id"`
+}
+
+func (q *Queries) FetchVisibleReplies(ctx context.Context, arg FetchVisibleRepliesParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, fetchVisibleReplies, arg.ParentID, arg.ViewerID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Message,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Verified,
+			&i.Visibility,
+			&i.NotebookID,
+			&i.Pinned,
+			&i.ParentID,
+			&i.Deleted,
+			&i.Hidden,
+			&i.Format,
+			&i.Revision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOldestNoteSince = `-- name: GetOldestNoteSince :one
+SELECT created_at FROM note_creations
+WHERE user_id = ? AND created_at >= ?
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type GetOldestNoteSinceParams struct {
+	UserID    string `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (q *Queries) GetOldestNoteSince(ctx context.Context, arg GetOldestNoteSinceParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getOldestNoteSince, arg.UserID, arg.CreatedAt)
+	var createdAt string
+	err := row.Scan(&createdAt)
+	return createdAt, err
+}
+
+const getUserUsage = `-- name: GetUserUsage :one
+SELECT count(*) AS notes, CAST(coalesce(sum(length(CAST(message AS BLOB))), 0) AS INTEGER) AS bytes, CAST((SELECT count(*) FROM note_creations WHERE note_creations.user_id = ? AND note_creations.created_at >= ?) AS INTEGER) AS notes_since
+FROM notes
+WHERE user_id = ? AND deleted = 0
+`
+
+type GetUserUsageParams struct {
+	UserID string `json:"user_id"`
+	Since  string `json:"since"`
+}
+
+type GetUserUsageRow struct {
+	Notes      int64 `json:"notes"`
+	Bytes      int64 `json:"bytes"`
+	NotesSince int64 `json:"notes_since"`
+}
+
+func (q *Queries) GetUserUsage(ctx context.Context, arg GetUserUsageParams) (GetUserUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserUsage, arg.UserID, arg.Since, arg.UserID)
+	var i GetUserUsageRow
+	err := row.Scan(
+		&i.Notes,
+		&i.Bytes,
+		&i.NotesSince,
+	)
+	return i, err
+}
+
+const moveNote = `-- name: MoveNote :exec
+UPDATE notes SET notebook_id = ?
+WHERE id = ? AND user_id = ?
+`
+
+type MoveNoteParams struct {
+	NotebookID string `json:"notebook_id"`
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+}
+
+func (q *Queries) MoveNote(ctx context.Context, arg MoveNoteParams) error {
+	_, err := q.db.ExecContext(ctx, moveNote, arg.NotebookID, arg.ID, arg.UserID)
+	return err
+}
+
+const setNoteHidden = `-- name: SetNoteHidden :exec
+UPDATE notes SET hidden = ?
+WHERE id = ?
+`
+
+type SetNoteHiddenParams struct {
+	Hidden int64  `json:"hidden"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) SetNoteHidden(ctx context.Context, arg SetNoteHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setNoteHidden, arg.Hidden, arg.ID)
+	return err
+}
+
+const setNotePinned = `-- name: SetNotePinned :exec
+UPDATE notes SET pinned = ?
+WHERE id = ?
+`
+
+type SetNotePinnedParams struct {
+	Pinned int64  `json:"pinned"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) SetNotePinned(ctx context.Context, arg SetNotePinnedParams) error {
+	_, err := q.db.ExecContext(ctx, setNotePinned, arg.Pinned, arg.ID)
+	return err
+}
+
+const setNoteVisibility = `-- name: SetNoteVisibility :exec
+UPDATE notes SET visibility = ?
+WHERE id = ?
+`
+
+type SetNoteVisibilityParams struct {
+	Visibility string `json:"visibility"`
+	ID         string `json:"id"`
+}
+
+func (q *Queries) SetNoteVisibility(ctx context.Context, arg SetNoteVisibilityParams) error {
+	_, err := q.db.ExecContext(ctx, setNoteVisibility, arg.Visibility, arg.ID)
+	return err
+}
+
+const softDeleteNote = `-- name: SoftDeleteNote :exec
+UPDATE notes SET author = '', message = '', pinned = 0, deleted = 1, revision = revision + 1
+WHERE id = ?
+`
+
+func (q *Queries) SoftDeleteNote(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, softDeleteNote, id)
+	return err
+}
+
 const updateNote = `-- name: UpdateNote :one
-UPDATE notes SET author = ?, message = ? 
+UPDATE notes SET author = ?, message = ?, visibility = ?, format = ?, revision = revision + 1
 WHERE id = ?
-RETURNING id, author, message, updated_at, created_at, user_id, verified
+RETURNING id, author, message, updated_at, created_at, user_id, verified, visibility, notebook_id, pinned, parent_id, deleted, hidden, format, revision
 `
 
 type UpdateNoteParams struct {
-	Author  string `json:"author"`
-	Message string `json:"message"`
-	ID      string `json:"id"`
+	Author     string `json:"author"`
+	Message    string `json:"message"`
+	Visibility string `json:"visibility"`
+	Format     string `json:"format"`
+	ID         string `json:"id"`
 }
 
 func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, error) {
-	row := q.db.QueryRowContext(ctx, updateNote, arg.Author, arg.Message, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateNote,
+		arg.Author,
+		arg.Message,
+		arg.Visibility,
+		arg.Format,
+		arg.ID,
+	)
 	var i Note
 	err := row.Scan(
 		&i.ID,
@@ -241,6 +777,14 @@ func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, e
 		&i.CreatedAt,
 		&i.UserID,
 		&i.Verified,
+		&i.Visibility,
+		&i.NotebookID,
+		&i.Pinned,
+		&i.ParentID,
+		&i.Deleted,
+		&i.Hidden,
+		&i.Format,
+		&i.Revision,
 	)
 	return i, err
 }
@@ -0,0 +1,28 @@
+-- name: UpsertCollaborator :one
+INSERT INTO note_collaborators (note_id, user_id, role)
+VALUES (?, ?, ?)
+ON CONFLICT (note_id, user_id) DO UPDATE SET role = excluded.role
+RETURNING *;
+
+-- name: GetCollaboratorRole :one
+SELECT role FROM note_collaborators
+WHERE note_id = ? AND user_id = ?;
+
+-- name: FetchNoteCollaborators :many
+SELECT users.id, users.username, users.name, note_collaborators.role, note_collaborators.created_at
+FROM note_collaborators
+JOIN users ON users.id = note_collaborators.user_id
+WHERE note_collaborators.note_id = ?
+ORDER BY note_collaborators.created_at ASC;
+
+-- name: DeleteCollaborator :execrows
+DELETE FROM note_collaborators
+WHERE note_id = ? AND user_id = ?;
+
+-- name: FetchSharedNotes :many
+SELECT notes.*, note_collaborators.role
+FROM notes
+JOIN note_collaborators ON note_collaborators.note_id = notes.id
+WHERE note_collaborators.user_id = ? AND notes.deleted = 0 AND notes.hidden = 0
+  AND notes.user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = note_collaborators.user_id)
+ORDER BY notes.created_at DESC;
@@ -0,0 +1,10 @@
+-- name: CreateEditToken :exec
+INSERT INTO note_edit_tokens (note_id, token_hash, expires_at)
+VALUES (?, ?, ?);
+
+-- name: GetEditToken :one
+SELECT * FROM note_edit_tokens
+WHERE note_id = ? AND expires_at > ?;
+
+-- name: DeleteExpiredEditTokens :exec
+DELETE FROM note_edit_tokens WHERE expires_at <= ?;
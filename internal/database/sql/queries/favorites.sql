@@ -0,0 +1,24 @@
+-- name: AddFavorite :exec
+INSERT INTO favorites (user_id, note_id)
+VALUES (?, ?)
+ON CONFLICT (user_id, note_id) DO NOTHING;
+
+-- name: DeleteFavorite :exec
+DELETE FROM favorites
+WHERE user_id = ? AND note_id = ?;
+
+-- name: FetchFavoriteNotes :many
+SELECT notes.* FROM favorites
+JOIN notes ON notes.id = favorites.note_id
+WHERE favorites.user_id = ? AND notes.deleted = 0
+  AND notes.user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = favorites.user_id)
+  AND (notes.hidden = 0 OR notes.user_id = favorites.user_id)
+  AND (
+  notes.visibility != 'private' OR
+  notes.user_id = favorites.user_id OR
+  EXISTS (
+    SELECT 1 FROM note_collaborators
+    WHERE note_collaborators.note_id = notes.id AND note_collaborators.user_id = favorites.user_id
+  )
+)
+ORDER BY favorites.created_at DESC;
@@ -0,0 +1,45 @@
+-- name: Follow :exec
+INSERT INTO follows (follower_id, followee_id)
+VALUES (?, ?)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: Unfollow :exec
+DELETE FROM follows
+WHERE follower_id = ? AND followee_id = ?;
+
+-- name: FetchFollowers :many
+SELECT users.id, users.username, users.name, follows.created_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = ?
+ORDER BY follows.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: FetchFollowing :many
+SELECT users.id, users.username, users.name, follows.created_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = ?
+ORDER BY follows.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountFollowers :one
+SELECT count(*) FROM follows
+WHERE followee_id = ?;
+
+-- name: CountFollowing :one
+SELECT count(*) FROM follows
+WHERE follower_id = ?;
+
+-- name: FetchFeed :many
+SELECT * FROM notes
+WHERE visibility = 'public' AND parent_id = '' AND deleted = 0
+  AND (created_at < sqlc.arg(before_created_at) OR (created_at = sqlc.arg(before_created_at) AND id < sqlc.arg(before_id)))
+  AND EXISTS (
+    SELECT 1 FROM follows
+    WHERE follows.follower_id = sqlc.arg(follower_id) AND follows.followee_id = notes.user_id
+  )
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(follower_id))
+  AND hidden = 0
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(limit);
@@ -0,0 +1,13 @@
+-- name: CreateIdentity :one
+INSERT INTO identities (id, user_id, provider, subject, email)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetIdentity :one
+SELECT * FROM identities
+WHERE provider = ? AND subject = ?;
+
+-- name: FetchUserIdentities :many
+SELECT * FROM identities
+WHERE user_id = ?
+ORDER BY created_at ASC;
@@ -0,0 +1,4 @@
+-- name: CreateLockout :one
+INSERT INTO lockouts (id, scope, subject, ip_address, failures, locked_until)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
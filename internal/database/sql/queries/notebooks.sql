@@ -0,0 +1,22 @@
+-- name: CreateNotebook :one
+INSERT INTO notebooks (id, user_id, name, description, position)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetNotebook :one
+SELECT * FROM notebooks
+WHERE id = ? AND user_id = ?;
+
+-- name: FetchNotebooks :many
+SELECT * FROM notebooks
+WHERE user_id = ?
+ORDER BY position ASC, created_at ASC;
+
+-- name: UpdateNotebook :one
+UPDATE notebooks SET name = ?, description = ?, position = ?
+WHERE id = ? AND user_id = ?
+RETURNING *;
+
+-- name: DeleteNotebook :execrows
+DELETE FROM notebooks
+WHERE id = ? AND user_id = ?;
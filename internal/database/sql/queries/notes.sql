@@ -1,10 +1,10 @@
 -- name: CreateNote :one
-INSERT INTO notes (id, author, message, user_id, verified) 
-VALUES (?, ?, ?, ?, ?) 
+INSERT INTO notes (id, author, message, user_id, verified, visibility, notebook_id, parent_id, format) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
 RETURNING *;
 
 -- name: UpdateNote :one
-UPDATE notes SET author = ?, message = ? 
+UPDATE notes SET author = ?, message = ?, visibility = ?, format = ?, revision = revision + 1
 WHERE id = ?
 RETURNING *;
 
@@ -13,21 +13,110 @@ DELETE FROM notes WHERE id = ?;
 
 -- name: FetchNotes :many
 SELECT * FROM notes
-ORDER BY created_at ASC;
+WHERE (visibility = 'public' OR (user_id = sqlc.arg(viewer_id) AND user_id != '')) AND parent_id = '' AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY pinned DESC, created_at ASC;
 
 -- name: FetchNotesDESC :many
 SELECT * FROM notes
-ORDER BY created_at DESC;
+WHERE (visibility = 'public' OR (user_id = sqlc.arg(viewer_id) AND user_id != '')) AND parent_id = '' AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY pinned DESC, created_at DESC;
 
 -- name: FetchNotesFromAuthor :many
 SELECT * FROM notes
-WHERE author = ?
-ORDER BY created_at ASC;
+WHERE author = ? AND (visibility = 'public' OR (user_id = sqlc.arg(viewer_id) AND user_id != '')) AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY pinned DESC, created_at ASC;
 
 -- name: FetchNotesFromAuthorDESC :many
 SELECT * FROM notes
-WHERE author = ?
-ORDER BY created_at DESC;
+WHERE author = ? AND (visibility = 'public' OR (user_id = sqlc.arg(viewer_id) AND user_id != '')) AND deleted = 0
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY pinned DESC, created_at DESC;
 
 -- name: FetchNoteByID :one
 SELECT * FROM notes WHERE id = ?;
+
+-- name: FetchNotebookNotes :many
+SELECT * FROM notes
+WHERE notebook_id = ? AND user_id = ? AND deleted = 0
+ORDER BY pinned DESC, created_at ASC;
+
+-- name: FetchNotebookNotesDESC :many
+SELECT * FROM notes
+WHERE notebook_id = ? AND user_id = ? AND deleted = 0
+ORDER BY pinned DESC, created_at DESC;
+
+-- name: MoveNote :exec
+UPDATE notes SET notebook_id = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: SetNotePinned :exec
+UPDATE notes SET pinned = ?
+WHERE id = ?;
+
+-- name: FetchReplies :many
+SELECT * FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY created_at ASC
+LIMIT ? OFFSET ?;
+
+-- name: CountVisibleReplies :one
+SELECT count(*) FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''));
+
+-- name: FetchVisibleReplies :many
+SELECT * FROM notes
+WHERE parent_id = ?
+  AND user_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = sqlc.arg(viewer_id))
+  AND (hidden = 0 OR (user_id = sqlc.arg(viewer_id) AND user_id != ''))
+ORDER BY created_at ASC;
+
+-- name: FetchAllReplies :many
+SELECT * FROM notes
+WHERE parent_id = ?
+ORDER BY created_at ASC;
+
+-- name: CountReplies :one
+SELECT count(*) FROM notes
+WHERE parent_id = ?;
+
+-- name: SoftDeleteNote :exec
+UPDATE notes SET author = '', message = '', pinned = 0, deleted = 1, revision = revision + 1
+WHERE id = ?;
+
+-- name: SetNoteVisibility :exec
+UPDATE notes SET visibility = ?
+WHERE id = ?;
+
+-- name: SetNoteHidden :exec
+UPDATE notes SET hidden = ?
+WHERE id = ?;
+
+-- name: FetchRecentMessages :many
+SELECT message FROM notes
+WHERE user_id = ? AND id != ? AND deleted = 0
+ORDER BY created_at DESC
+LIMIT 20;
+
+-- name: GetUserUsage :one
+SELECT count(*) AS notes,
+  CAST(coalesce(sum(length(CAST(message AS BLOB))), 0) AS INTEGER) AS bytes,
+  CAST((SELECT count(*) FROM note_creations WHERE note_creations.user_id = sqlc.arg(user_id) AND note_creations.created_at >= sqlc.arg(since)) AS INTEGER) AS notes_since
+FROM notes
+WHERE user_id = sqlc.arg(user_id) AND deleted = 0;
+
+-- name: GetOldestNoteSince :one
+SELECT created_at FROM note_creations
+WHERE user_id = ? AND created_at >= ?
+ORDER BY created_at ASC
+LIMIT 1;
@@ -0,0 +1,54 @@
+-- name: AddMention :execrows
+INSERT INTO mentions (note_id, user_id)
+VALUES (?, ?)
+ON CONFLICT (note_id, user_id) DO NOTHING;
+
+-- name: DeleteMention :exec
+DELETE FROM mentions
+WHERE note_id = ? AND user_id = ?;
+
+-- name: FetchNoteMentions :many
+SELECT user_id FROM mentions
+WHERE note_id = ?;
+
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, user_id, type, actor_id, note_id, emoji)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: FetchNotifications :many
+SELECT notifications.*, users.username AS actor_username, users.name AS actor_name
+FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = ?
+  AND notifications.actor_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = notifications.user_id)
+ORDER BY notifications.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: FetchUnreadNotifications :many
+SELECT notifications.*, users.username AS actor_username, users.name AS actor_name
+FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = ? AND notifications.read = 0
+  AND notifications.actor_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = notifications.user_id)
+ORDER BY notifications.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountNotifications :one
+SELECT count(*) FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = ?
+  AND notifications.actor_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = notifications.user_id);
+
+-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = ? AND notifications.read = 0
+  AND notifications.actor_id NOT IN (SELECT target_id FROM user_restrictions WHERE user_restrictions.user_id = notifications.user_id);
+
+-- name: SetNotificationRead :execrows
+UPDATE notifications SET read = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read = 1
+WHERE user_id = ? AND read = 0;
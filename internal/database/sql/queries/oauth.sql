@@ -0,0 +1,42 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, user_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients WHERE id = ?;
+
+-- name: FetchUserOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients WHERE id = ? AND user_id = ?;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scope, code_challenge, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ConsumeOAuthCode :one
+DELETE FROM oauth_codes WHERE code_hash = ? AND client_id = ?
+RETURNING *;
+
+-- name: CreateOAuthToken :exec
+INSERT INTO oauth_tokens (id, token_hash, kind, grant_id, client_id, user_id, scope, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetOAuthToken :one
+SELECT * FROM oauth_tokens WHERE token_hash = ?;
+
+-- name: RevokeOAuthGrant :exec
+UPDATE oauth_tokens SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE grant_id = ? AND revoked_at IS NULL;
+
+-- name: RevokeOAuthClientTokens :exec
+UPDATE oauth_tokens SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE client_id = ? AND revoked_at IS NULL;
+
+-- name: RevokeOAuthToken :execrows
+UPDATE oauth_tokens SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE id = ? AND revoked_at IS NULL;
@@ -0,0 +1,30 @@
+-- name: CreatePasskey :one
+INSERT INTO passkeys (id, user_id, credential_id, public_key, sign_count, name)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: FetchUserPasskeys :many
+SELECT * FROM passkeys
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: GetPasskeyByCredentialID :one
+SELECT * FROM passkeys WHERE credential_id = ?;
+
+-- name: UpdatePasskeySignCount :exec
+UPDATE passkeys SET sign_count = ?, last_used_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE id = ?;
+
+-- name: DeletePasskey :execrows
+DELETE FROM passkeys WHERE id = ? AND user_id = ?;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge, ceremony, user_id, expires_at)
+VALUES (?, ?, ?, ?);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges WHERE challenge = ?
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM webauthn_challenges WHERE expires_at < ?;
@@ -0,0 +1,6 @@
+-- name: UsePowChallenge :execrows
+INSERT INTO pow_challenges (challenge, expires_at) VALUES (?, ?)
+ON CONFLICT (challenge) DO NOTHING;
+
+-- name: DeleteExpiredPowChallenges :exec
+DELETE FROM pow_challenges WHERE expires_at < ?;
@@ -0,0 +1,12 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits (key, tokens, allowed, updated_at)
+VALUES (CAST(sqlc.arg(key) AS TEXT), CAST(sqlc.arg(burst) AS REAL) - 1, 1, CAST(sqlc.arg(now) AS REAL))
+ON CONFLICT (key) DO UPDATE SET
+  allowed = min(sqlc.arg(burst), tokens + max(excluded.updated_at - updated_at, 0) * CAST(sqlc.arg(rate) AS REAL)) >= 1,
+  tokens = min(sqlc.arg(burst), tokens + max(excluded.updated_at - updated_at, 0) * sqlc.arg(rate))
+    - (min(sqlc.arg(burst), tokens + max(excluded.updated_at - updated_at, 0) * sqlc.arg(rate)) >= 1),
+  updated_at = max(excluded.updated_at, updated_at)
+RETURNING tokens, allowed;
+
+-- name: DeleteStaleRateLimits :exec
+DELETE FROM rate_limits WHERE updated_at < ?;
@@ -0,0 +1,15 @@
+-- name: AddReaction :execrows
+INSERT INTO note_reactions (note_id, user_id, emoji)
+VALUES (?, ?, ?)
+ON CONFLICT (note_id, user_id, emoji) DO NOTHING;
+
+-- name: DeleteReaction :exec
+DELETE FROM note_reactions
+WHERE note_id = ? AND user_id = ? AND emoji = ?;
+
+-- name: FetchNoteReactions :many
+SELECT emoji, count(*) AS count, CAST(max(user_id = sqlc.arg(viewer_id)) AS INTEGER) AS reacted
+FROM note_reactions
+WHERE note_id = sqlc.arg(note_id)
+GROUP BY emoji
+ORDER BY count DESC, min(created_at) ASC;
@@ -0,0 +1,35 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, note_id, reporter_id, reason, details)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: CountOpenReports :one
+SELECT count(*) FROM reports
+WHERE note_id = ? AND status = 'open';
+
+-- name: FetchReportByID :one
+SELECT * FROM reports WHERE id = ?;
+
+-- name: FetchReports :many
+SELECT * FROM reports
+WHERE status = ?
+ORDER BY created_at ASC
+LIMIT ? OFFSET ?;
+
+-- name: CountReports :one
+SELECT count(*) FROM reports
+WHERE status = ?;
+
+-- name: ResolveReports :exec
+UPDATE reports
+SET status = ?, action = ?, moderator_id = ?, moderator_note = ?,
+  resolved_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE note_id = ? AND status = 'open';
+
+-- name: FlagNote :exec
+INSERT INTO reports (id, note_id, reporter_id, reason, details)
+VALUES (?, ?, '', ?, ?)
+ON CONFLICT (note_id, reporter_id) DO UPDATE
+SET reason = excluded.reason, details = excluded.details, status = 'open', action = '',
+  moderator_id = '', moderator_note = '', resolved_at = '',
+  created_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now');
@@ -0,0 +1,29 @@
+-- name: AddRestriction :exec
+INSERT INTO user_restrictions (user_id, target_id, kind)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, target_id, kind) DO NOTHING;
+
+-- name: DeleteRestriction :exec
+DELETE FROM user_restrictions
+WHERE user_id = ? AND target_id = ? AND kind = ?;
+
+-- name: FetchRestrictedUsers :many
+SELECT users.id, users.username, users.name, user_restrictions.created_at
+FROM user_restrictions
+JOIN users ON users.id = user_restrictions.target_id
+WHERE user_restrictions.user_id = ? AND user_restrictions.kind = ?
+ORDER BY user_restrictions.created_at DESC;
+
+-- name: HasBlocked :one
+SELECT count(*) FROM user_restrictions
+WHERE user_id = ? AND target_id = ? AND kind = 'block';
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_a) AND followee_id = sqlc.arg(user_b))
+  OR (follower_id = sqlc.arg(user_b) AND followee_id = sqlc.arg(user_a));
+
+-- name: DeleteCollaborationsBetween :exec
+DELETE FROM note_collaborators
+WHERE (user_id = sqlc.arg(user_a) AND note_id IN (SELECT id FROM notes WHERE notes.user_id = sqlc.arg(user_b)))
+  OR (user_id = sqlc.arg(user_b) AND note_id IN (SELECT id FROM notes WHERE notes.user_id = sqlc.arg(user_a)));
@@ -0,0 +1,24 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip_address)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions WHERE id = ?;
+
+-- name: FetchActiveSessions :many
+SELECT * FROM sessions
+WHERE user_id = ? AND revoked_at IS NULL
+ORDER BY last_seen_at DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions SET last_seen_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now'), ip_address = ?
+WHERE id = ? AND last_seen_at < ?;
+
+-- name: RevokeSession :execrows
+UPDATE sessions SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL;
+
+-- name: RevokeAllSessions :exec
+UPDATE sessions SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE user_id = ? AND revoked_at IS NULL;
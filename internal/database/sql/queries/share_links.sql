@@ -0,0 +1,21 @@
+-- name: CreateShareLink :one
+INSERT INTO share_links (id, note_id, token_hash, password, max_views, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetShareLinkByToken :one
+SELECT * FROM share_links WHERE token_hash = ?;
+
+-- name: FetchNoteShareLinks :many
+SELECT * FROM share_links
+WHERE note_id = ? AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: CountShareLinkView :one
+UPDATE share_links SET views = views + 1
+WHERE id = ? AND revoked_at IS NULL AND (max_views IS NULL OR views < max_views)
+RETURNING *;
+
+-- name: RevokeShareLink :execrows
+UPDATE share_links SET revoked_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE id = ? AND note_id = ? AND revoked_at IS NULL;
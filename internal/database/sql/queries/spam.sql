@@ -0,0 +1,15 @@
+-- name: GetSpamToken :one
+SELECT * FROM spam_tokens WHERE token = ?;
+
+-- name: AddSpamToken :exec
+INSERT INTO spam_tokens (token, spam, ham)
+VALUES (?, ?, ?)
+ON CONFLICT (token) DO UPDATE SET spam = spam + excluded.spam, ham = ham + excluded.ham;
+
+-- name: FetchSpamDocuments :many
+SELECT * FROM spam_documents;
+
+-- name: AddSpamDocument :exec
+INSERT INTO spam_documents (class, documents)
+VALUES (?, 1)
+ON CONFLICT (class) DO UPDATE SET documents = documents + 1;
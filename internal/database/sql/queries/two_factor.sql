@@ -0,0 +1,30 @@
+-- name: SetupTwoFactor :one
+INSERT INTO two_factor (user_id, secret)
+VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled = 0, last_used_step = 0
+RETURNING *;
+
+-- name: GetTwoFactor :one
+SELECT * FROM two_factor WHERE user_id = ?;
+
+-- name: EnableTwoFactor :execrows
+UPDATE two_factor SET enabled = 1, last_used_step = ?
+WHERE user_id = ? AND enabled = 0;
+
+-- name: UpdateTwoFactorStep :execrows
+UPDATE two_factor SET last_used_step = sqlc.arg(last_used_step)
+WHERE user_id = sqlc.arg(user_id) AND last_used_step < sqlc.arg(last_used_step);
+
+-- name: DeleteTwoFactor :exec
+DELETE FROM two_factor WHERE user_id = ?;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash)
+VALUES (?, ?, ?);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = ?;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+WHERE user_id = ? AND code_hash = ? AND used_at IS NULL;
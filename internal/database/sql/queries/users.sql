@@ -3,5 +3,27 @@ INSERT INTO users (id, email, name, username, password)
 VALUES (?, ?, ?, ?, ?)
 RETURNING *;
 
+-- name: SetUserVerified :exec
+UPDATE users SET verified = ?
+WHERE id = ?;
+
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = ?;
\ No newline at end of file
+SELECT * FROM users WHERE email = ?;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET password = ?
+WHERE id = ?;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = ?;
+
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE username = ?;
+
+-- name: SetUserRole :execrows
+UPDATE users SET role = ?
+WHERE username = ?;
+
+-- name: SetUserSuspended :exec
+UPDATE users SET suspended = ?
+WHERE id = ?;
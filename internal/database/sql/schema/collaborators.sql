@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS note_collaborators (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  role TEXT NOT NULL CHECK(role IN ('viewer', 'editor')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS note_collaborators_user_id_idx ON note_collaborators (user_id);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_collaborators
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_collaborators WHERE note_id = OLD.id;
+END;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS note_edit_tokens (
+  note_id TEXT NOT NULL PRIMARY KEY,
+  token_hash TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_edit_tokens
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_edit_tokens WHERE note_id = OLD.id;
+END;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS favorites (
+  user_id TEXT NOT NULL,
+  note_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (user_id, note_id)
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_favorites
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM favorites WHERE note_id = OLD.id;
+END;
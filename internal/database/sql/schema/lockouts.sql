@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS lockouts (
+  id TEXT NOT NULL PRIMARY KEY,
+  scope TEXT NOT NULL CHECK(scope IN ('account', 'ip')),
+  subject TEXT NOT NULL,
+  ip_address TEXT NOT NULL,
+  failures INTEGER NOT NULL,
+  locked_until TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS lockouts_subject_idx ON lockouts (subject);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS notebooks (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL CHECK(
+    length(name) >= 1 AND
+    length(name) <= 100
+  ),
+  description TEXT NOT NULL DEFAULT '',
+  position INTEGER NOT NULL DEFAULT 0,
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_idx ON notebooks (user_id);
+
+CREATE TRIGGER IF NOT EXISTS update_notebook_timestamp
+AFTER UPDATE ON notebooks
+FOR EACH ROW
+BEGIN
+  UPDATE notebooks
+  SET updated_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
+  WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_notebook_notes
+AFTER DELETE ON notebooks
+FOR EACH ROW
+BEGIN
+  UPDATE notes SET notebook_id = '' WHERE notebook_id = OLD.id;
+END;
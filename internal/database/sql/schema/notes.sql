@@ -5,9 +5,25 @@ CREATE TABLE IF NOT EXISTS notes (
   updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
   user_id TEXT NOT NULL,
-  verified INTEGER NOT NULL DEFAULT 0
+  verified INTEGER NOT NULL DEFAULT 0,
+  visibility TEXT NOT NULL DEFAULT 'public' CHECK(
+    visibility IN ('public', 'unlisted', 'private')
+  ),
+  notebook_id TEXT NOT NULL DEFAULT '',
+  pinned INTEGER NOT NULL DEFAULT 0,
+  parent_id TEXT NOT NULL DEFAULT '',
+  deleted INTEGER NOT NULL DEFAULT 0,
+  hidden INTEGER NOT NULL DEFAULT 0,
+  format TEXT NOT NULL DEFAULT 'plain' CHECK(format IN ('plain', 'markdown')),
+  revision INTEGER NOT NULL DEFAULT 1
 );
 
+CREATE INDEX IF NOT EXISTS notes_created_at_idx ON notes (created_at, id);
+
+CREATE INDEX IF NOT EXISTS notes_user_id_idx ON notes (user_id, created_at);
+
+-- notes_parent_id_idx is created by migrateDB, once older databases have the parent_id column
+
 CREATE TRIGGER IF NOT EXISTS update_note_timestamp
 AFTER UPDATE ON notes
 FOR EACH ROW
@@ -15,4 +31,22 @@ BEGIN
   UPDATE notes
   SET updated_at = strftime('%Y-%m-%d %H:%M:%fZ', 'now')
   WHERE id = NEW.id;
-END;
\ No newline at end of file
+END;
+
+-- Notes created by users in the last day, kept after the notes are deleted so that
+-- deleting notes doesn't make room in the daily limit
+CREATE TABLE IF NOT EXISTS note_creations (
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS note_creations_user_id_idx ON note_creations (user_id, created_at);
+
+CREATE TRIGGER IF NOT EXISTS log_note_creation
+AFTER INSERT ON notes
+FOR EACH ROW WHEN NEW.user_id != ''
+BEGIN
+  DELETE FROM note_creations
+  WHERE user_id = NEW.user_id AND created_at < strftime('%Y-%m-%d %H:%M:%fZ', 'now', '-1 day');
+  INSERT INTO note_creations (user_id, created_at) VALUES (NEW.user_id, NEW.created_at);
+END;
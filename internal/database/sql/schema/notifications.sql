@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS mentions (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  type TEXT NOT NULL CHECK(type IN ('mention', 'reply', 'reaction')),
+  actor_id TEXT NOT NULL,
+  note_id TEXT NOT NULL,
+  emoji TEXT NOT NULL DEFAULT '',
+  read INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_notifications
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM mentions WHERE note_id = OLD.id;
+  DELETE FROM notifications WHERE note_id = OLD.id;
+END;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL CHECK(
+    length(name) >= 2 AND
+    length(name) <= 50
+  ),
+  secret_hash TEXT NOT NULL,
+  redirect_uris TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS oauth_codes (
+  code_hash TEXT NOT NULL PRIMARY KEY,
+  client_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  redirect_uri TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  code_challenge TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS oauth_tokens (
+  id TEXT NOT NULL PRIMARY KEY,
+  token_hash TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL CHECK(kind IN ('access', 'refresh')),
+  grant_id TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  revoked_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS oauth_tokens_grant_id_idx ON oauth_tokens (grant_id);
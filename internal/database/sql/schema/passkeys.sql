@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  credential_id TEXT NOT NULL UNIQUE,
+  public_key BLOB NOT NULL,
+  sign_count INTEGER NOT NULL DEFAULT 0,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  last_used_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+  challenge TEXT NOT NULL PRIMARY KEY,
+  ceremony TEXT NOT NULL CHECK(ceremony IN ('registration', 'authentication')),
+  user_id TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
@@ -0,0 +1,7 @@
+-- Used proof-of-work challenges until they expire, expires_at is Unix time in seconds
+CREATE TABLE IF NOT EXISTS pow_challenges (
+  challenge TEXT NOT NULL PRIMARY KEY,
+  expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pow_challenges_expires_at_idx ON pow_challenges (expires_at);
@@ -0,0 +1,7 @@
+-- Token buckets of the rate limiter, updated_at is Unix time in seconds
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key TEXT NOT NULL PRIMARY KEY,
+  tokens REAL NOT NULL,
+  allowed INTEGER NOT NULL,
+  updated_at REAL NOT NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS note_reactions (
+  note_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  emoji TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (note_id, user_id, emoji)
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_reactions
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM note_reactions WHERE note_id = OLD.id;
+END;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS reports (
+  id TEXT NOT NULL PRIMARY KEY,
+  note_id TEXT NOT NULL,
+  reporter_id TEXT NOT NULL,
+  reason TEXT NOT NULL CHECK(
+    reason IN ('spam', 'harassment', 'hate', 'sexual', 'violence', 'other')
+  ),
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open' CHECK(status IN ('open', 'dismissed', 'resolved')),
+  action TEXT NOT NULL DEFAULT '',
+  moderator_id TEXT NOT NULL DEFAULT '',
+  moderator_note TEXT NOT NULL DEFAULT '',
+  resolved_at TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (note_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, created_at);
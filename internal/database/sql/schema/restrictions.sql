@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_restrictions (
+  user_id TEXT NOT NULL,
+  target_id TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK(kind IN ('mute', 'block')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  PRIMARY KEY (user_id, target_id, kind)
+);
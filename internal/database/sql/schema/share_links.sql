@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS share_links (
+  id TEXT NOT NULL PRIMARY KEY,
+  note_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  password TEXT,
+  max_views INTEGER,
+  views INTEGER NOT NULL DEFAULT 0,
+  expires_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  revoked_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS share_links_note_id_idx ON share_links (note_id);
+
+CREATE TRIGGER IF NOT EXISTS delete_note_share_links
+AFTER DELETE ON notes
+FOR EACH ROW
+BEGIN
+  DELETE FROM share_links WHERE note_id = OLD.id;
+END;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS spam_tokens (
+  token TEXT NOT NULL PRIMARY KEY,
+  spam INTEGER NOT NULL DEFAULT 0,
+  ham INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS spam_documents (
+  class TEXT NOT NULL PRIMARY KEY CHECK(class IN ('spam', 'ham')),
+  documents INTEGER NOT NULL DEFAULT 0
+);
@@ -12,7 +12,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email, name, username, password)
 VALUES (?, ?, ?, ?, ?)
-RETURNING id, email, name, username, password, updated_at, created_at, verified
+RETURNING id, email, name, username, password, updated_at, created_at, verified, role, suspended
 `
 
 type CreateUserParams struct {
@@ -41,12 +41,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.Verified,
+		&i.Role,
+		&i.Suspended,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, name, username, password, updated_at, created_at, verified FROM users WHERE email = ?
+SELECT id, email, name, username, password, updated_at, created_at, verified, role, suspended FROM users WHERE email = ?
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -61,6 +63,115 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.Verified,
+		&i.Role,
+		&i.Suspended,
 	)
 	return i, err
 }
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, name, username, password, updated_at, created_at, verified, role, suspended FROM users WHERE id = ?
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Username,
+		&i.Password,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.Verified,
+		&i.Role,
+		&i.Suspended,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, email, name, username, password, updated_at, created_at, verified, role, suspended FROM users WHERE username = ?
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Username,
+		&i.Password,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.Verified,
+		&i.Role,
+		&i.Suspended,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users SET role = ?
+WHERE username = ?
+`
+
+type SetUserRoleParams struct {
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Role, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserSuspended = `-- name: SetUserSuspended :exec
+UPDATE users SET suspended = ?
+WHERE id = ?
+`
+
+type SetUserSuspendedParams struct {
+	Suspended int64  `json:"suspended"`
+	ID        string `json:"id"`
+}
+
+func (q *Queries) SetUserSuspended(ctx context.Context, arg SetUserSuspendedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserSuspended, arg.Suspended, arg.ID)
+	return err
+}
+
+const setUserVerified = `-- name: SetUserVerified :exec
+UPDATE users SET verified = ?
+WHERE id = ?
+`
+
+type SetUserVerifiedParams struct {
+	Verified int64  `json:"verified"`
+	ID       string `json:"id"`
+}
+
+func (q *Queries) SetUserVerified(ctx context.Context, arg SetUserVerifiedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserVerified, arg.Verified, arg.ID)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET password = ?
+WHERE id = ?
+`
+
+type UpdateUserPasswordParams struct {
+	Password string `json:"password"`
+	ID       string `json:"id"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.Password, arg.ID)
+	return err
+}
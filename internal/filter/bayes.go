@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// maxTokens limits how many distinct tokens of a message are looked at.
+const maxTokens = 200
+
+// TokenCounts is the number of spam and ham messages a token was seen in.
+type TokenCounts struct {
+	Spam int64
+	Ham  int64
+}
+
+// BayesStore persists what the classifier has learned.
+type BayesStore interface {
+	// Documents returns the number of spam and ham messages the classifier was trained on
+	Documents(ctx context.Context) (spam, ham int64, err error)
+	// Tokens returns counts of the tokens, leaving out tokens that were never seen
+	Tokens(ctx context.Context, tokens []string) (map[string]TokenCounts, error)
+	// Train counts the tokens of a message and the message itself as spam or ham
+	Train(ctx context.Context, tokens []string, spam bool) error
+}
+
+// Bayes is a naive Bayes spam classifier. It flags notes whose probability of being spam
+// is at least Threshold, once it was trained on MinDocuments messages of each kind.
+type Bayes struct {
+	Store        BayesStore
+	Threshold    float64
+	MinDocuments int64
+}
+
+func (f *Bayes) Name() string { return "spam" }
+
+func (f *Bayes) Check(ctx context.Context, note *Note) (Verdict, error) {
+	p, trained, err := f.SpamProbability(ctx, note.Message)
+	if err != nil || !trained || p < f.Threshold {
+		return Verdict{}, err
+	}
+	return Verdict{Action: Flag, Category: "spam", Reason: "Message looks like spam"}, nil
+}
+
+// Train teaches the classifier that the message is spam or not.
+func (f *Bayes) Train(ctx context.Context, message string, spam bool) error {
+	return f.Store.Train(ctx, Tokenize(message), spam)
+}
+
+// SpamProbability returns the probability that the message is spam. It returns false
+// if the classifier hasn't seen enough messages yet.
+func (f *Bayes) SpamProbability(ctx context.Context, message string) (float64, bool, error) {
+	spamDocs, hamDocs, err := f.Store.Documents(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if spamDocs < max(f.MinDocuments, 1) || hamDocs < max(f.MinDocuments, 1) {
+		return 0, false, nil
+	}
+
+	tokens := Tokenize(message)
+	counts, err := f.Store.Tokens(ctx, tokens)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Sum log odds to avoid underflow, with Laplace smoothing for unseen tokens
+	logOdds := math.Log(float64(spamDocs)) - math.Log(float64(hamDocs))
+	for _, t := range tokens {
+		c := counts[t]
+		pSpam := float64(c.Spam+1) / float64(spamDocs+2)
+		pHam := float64(c.Ham+1) / float64(hamDocs+2)
+		logOdds += math.Log(pSpam) - math.Log(pHam)
+	}
+	return 1 / (1 + math.Exp(-logOdds)), true, nil
+}
+
+// Tokenize returns distinct lowercase words of the message.
+func Tokenize(message string) []string {
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, w := range words {
+		if len(w) < 2 || len(w) > 30 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		tokens = append(tokens, w)
+		if len(tokens) == maxTokens {
+			break
+		}
+	}
+	return tokens
+}
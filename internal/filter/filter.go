@@ -0,0 +1,201 @@
+// Package filter checks the content of notes before they are saved.
+//
+// A Pipeline runs a list of filters in order. Each filter may let the note through,
+// change its message, send it to moderation or reject it.
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action is what a filter decided to do with a note.
+type Action int
+
+const (
+	// Allow lets the note through, possibly with a modified message
+	Allow Action = iota
+	// Flag saves the note, but sends it to moderation
+	Flag
+	// Reject refuses to save the note
+	Reject
+)
+
+// Note is the content being checked.
+type Note struct {
+	// ID is empty for new notes
+	ID       string
+	AuthorID string
+	Message  string
+}
+
+// Verdict is the outcome of a filter.
+type Verdict struct {
+	Action Action
+	// Filter is the name of the filter that made the decision
+	Filter string
+	// Category is the report reason used when the note is flagged
+	Category string
+	// Reason explains the decision to the user or to moderators
+	Reason string
+}
+
+// Filter checks a note. Filters may change the message of the note.
+type Filter interface {
+	Name() string
+	Check(ctx context.Context, note *Note) (Verdict, error)
+}
+
+// Pipeline runs filters in order. It stops at the first rejection; a flag is remembered,
+// but the following filters still run, so they can modify or reject the note.
+type Pipeline []Filter
+
+// Run checks the note and returns the strongest verdict of the filters.
+func (p Pipeline) Run(ctx context.Context, note *Note) (Verdict, error) {
+	result := Verdict{Action: Allow}
+	for _, f := range p {
+		v, err := f.Check(ctx, note)
+		if err != nil {
+			return Verdict{}, fmt.Errorf("%s filter: %w", f.Name(), err)
+		}
+		if v.Action == Allow {
+			continue
+		}
+		v.Filter = f.Name()
+		if v.Action == Reject {
+			return v, nil
+		}
+		if result.Action == Allow {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+// ProfanityMode is what the profanity filter does with words from its list.
+type ProfanityMode string
+
+const (
+	ProfanityReject ProfanityMode = "reject"
+	ProfanityMask   ProfanityMode = "mask"
+	ProfanityFlag   ProfanityMode = "flag"
+)
+
+// Profanity matches whole words from a list, ignoring case.
+type Profanity struct {
+	Mode    ProfanityMode
+	pattern *regexp.Regexp
+}
+
+// NewProfanity returns a profanity filter for the words, or nil if there are no words.
+func NewProfanity(words []string, mode ProfanityMode) *Profanity {
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return &Profanity{
+		Mode:    mode,
+		pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+	}
+}
+
+func (f *Profanity) Name() string { return "profanity" }
+
+func (f *Profanity) Check(ctx context.Context, note *Note) (Verdict, error) {
+	if !f.pattern.MatchString(note.Message) {
+		return Verdict{}, nil
+	}
+	switch f.Mode {
+	case ProfanityReject:
+		return Verdict{Action: Reject, Category: "other", Reason: "Message contains disallowed words"}, nil
+	case ProfanityFlag:
+		return Verdict{Action: Flag, Category: "other", Reason: "Message contains disallowed words"}, nil
+	}
+	note.Message = f.pattern.ReplaceAllStringFunc(note.Message, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	})
+	return Verdict{}, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// Links flags notes with more than Max links.
+type Links struct {
+	Max int
+}
+
+func (f *Links) Name() string { return "links" }
+
+func (f *Links) Check(ctx context.Context, note *Note) (Verdict, error) {
+	n := len(linkPattern.FindAllStringIndex(note.Message, -1))
+	if n <= f.Max {
+		return Verdict{}, nil
+	}
+	return Verdict{
+		Action:   Flag,
+		Category: "spam",
+		Reason:   fmt.Sprintf("Message contains %d links", n),
+	}, nil
+}
+
+// Repeats collapses runs of the same character longer than MaxRun
+// and rejects notes that repeat one of the author's recent notes.
+type Repeats struct {
+	MaxRun int
+	// Recent returns recent messages of the author other than the note itself
+	Recent func(ctx context.Context, note *Note) ([]string, error)
+}
+
+func (f *Repeats) Name() string { return "repeats" }
+
+func (f *Repeats) Check(ctx context.Context, note *Note) (Verdict, error) {
+	if f.MaxRun > 0 {
+		note.Message = collapseRuns(note.Message, f.MaxRun)
+	}
+	if f.Recent == nil {
+		return Verdict{}, nil
+	}
+
+	recent, err := f.Recent(ctx, note)
+	if err != nil {
+		return Verdict{}, err
+	}
+	message := normalize(note.Message)
+	for _, m := range recent {
+		if normalize(m) == message {
+			return Verdict{Action: Reject, Category: "spam", Reason: "Message repeats one of your recent notes"}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// collapseRuns shortens runs of the same character to max characters.
+func collapseRuns(s string, max int) string {
+	var b strings.Builder
+	var prev rune
+	run := 0
+	for _, r := range s {
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if run <= max {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// normalize makes messages that differ only in case and spacing equal.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
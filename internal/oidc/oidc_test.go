@@ -0,0 +1,154 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testRedirectURL = "https://notes.example/users/oidc/test/callback"
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+	srv := oidctest.NewServer(t, "annynotes")
+	p := NewProvider(Config{
+		Name:        "test",
+		Issuer:      srv.Issuer(),
+		ClientID:    srv.ClientID,
+		RedirectURL: testRedirectURL,
+	}, srv.Client())
+	return p, srv
+}
+
+// authorize follows the redirect to the provider and returns the query it redirected back with.
+func authorize(t *testing.T, p *Provider, state, nonce, verifier string) url.Values {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, CodeChallenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization responded with %s", resp.Status)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %q, want %q", got, testRedirectURL)
+	}
+	return location.Query()
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	p, srv := newTestProvider(t)
+	srv.SetClaims(jwt.MapClaims{"sub": "user-1", "email": "ann@example.com", "email_verified": true})
+
+	query := authorize(t, p, "state-1", "nonce-1", "verifier-1")
+	if query.Get("state") != "state-1" {
+		t.Errorf("state = %q, want %q", query.Get("state"), "state-1")
+	}
+
+	idToken, err := p.Exchange(context.Background(), query.Get("code"), "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idToken.Subject != "user-1" || idToken.Email != "ann@example.com" || !idToken.EmailVerified {
+		t.Errorf("unexpected claims: %+v", idToken)
+	}
+
+	// Codes can be redeemed once
+	if _, err := p.Exchange(context.Background(), query.Get("code"), "verifier-1", "nonce-1"); err == nil {
+		t.Error("code was redeemed twice")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	p, srv := newTestProvider(t)
+	srv.SetClaims(jwt.MapClaims{"sub": "user-1"})
+
+	query := authorize(t, p, "state", "nonce", "verifier")
+	if _, err := p.Exchange(context.Background(), query.Get("code"), "another-verifier", "nonce"); err == nil {
+		t.Error("code was redeemed with a wrong verifier")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	p, srv := newTestProvider(t)
+	srv.SetClaims(jwt.MapClaims{"sub": "user-1"})
+
+	query := authorize(t, p, "state", "nonce", "verifier")
+	if _, err := p.Exchange(context.Background(), query.Get("code"), "verifier", "another-nonce"); err == nil {
+		t.Error("ID token with a wrong nonce was accepted")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p, srv := newTestProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		change func(jwt.MapClaims)
+		ok     bool
+	}{
+		{"valid", srv.Key, func(jwt.MapClaims) {}, true},
+		{"bad signature", otherKey, func(jwt.MapClaims) {}, false},
+		{"wrong audience", srv.Key, func(c jwt.MapClaims) { c["aud"] = "another-client" }, false},
+		{"wrong issuer", srv.Key, func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, false},
+		{"wrong nonce", srv.Key, func(c jwt.MapClaims) { c["nonce"] = "another-nonce" }, false},
+		{"expired", srv.Key, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, false},
+		{"no expiry", srv.Key, func(c jwt.MapClaims) { delete(c, "exp") }, false},
+		{"no subject", srv.Key, func(c jwt.MapClaims) { delete(c, "sub") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := srv.IDTokenClaims("user-1", "nonce")
+			tt.change(claims)
+			raw, err := oidctest.Sign(tt.key, claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = p.VerifyIDToken(context.Background(), raw, "nonce")
+			if tt.ok && err != nil {
+				t.Errorf("valid ID token was rejected: %s", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("invalid ID token was accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsUnsignedToken(t *testing.T) {
+	p, srv := newTestProvider(t)
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, srv.IDTokenClaims("user-1", "nonce"))
+	token.Header["kid"] = oidctest.KeyID
+	raw, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(context.Background(), raw, "nonce"); err == nil {
+		t.Error("unsigned ID token was accepted")
+	}
+}
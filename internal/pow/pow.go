@@ -0,0 +1,234 @@
+// Package pow implements a hashcash-style proof-of-work challenge.
+//
+// A client gets a challenge and looks for a counter such that the SHA-256 hash of
+// "<challenge>:<counter>" starts with the required number of zero bits, then sends
+// "<challenge>:<counter>" along with the request. Challenges are signed, so the server
+// doesn't store them until they are used, and each challenge can be used once. Used
+// challenges are kept in a Store until they expire.
+package pow
+
+import (
+	"container/heap"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMalformed = errors.New("malformed solution")
+	ErrInvalid   = errors.New("invalid challenge")
+	ErrExpired   = errors.New("challenge has expired")
+	ErrUsed      = errors.New("challenge was already used")
+	ErrWrong     = errors.New("solution doesn't meet the difficulty")
+)
+
+// Challenge is a puzzle for the client to solve.
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	Algorithm  string    `json:"algorithm"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Store keeps used challenges until they expire.
+type Store interface {
+	// Use marks the challenge as used until it expires. It returns false if the challenge
+	// was already used.
+	Use(ctx context.Context, challenge string, expiresAt time.Time) (bool, error)
+}
+
+// Issuer issues and verifies challenges. Difficulty is the number of leading zero bits
+// of the hash. It starts at BaseDifficulty and grows by one bit every time the number
+// of challenges issued in the last minute doubles past Threshold, up to MaxDifficulty.
+type Issuer struct {
+	BaseDifficulty int
+	MaxDifficulty  int
+	Threshold      int
+	TTL            time.Duration
+
+	key   []byte
+	store Store
+
+	mu sync.Mutex
+	// Challenges issued per second over the last minute
+	issued [60]struct {
+		second int64
+		count  int
+	}
+}
+
+// NewIssuer returns an issuer signing challenges with the key and keeping used ones in the store.
+// Issuers with the same key and store accept challenges of each other.
+func NewIssuer(key []byte, store Store, baseDifficulty, maxDifficulty, threshold int) *Issuer {
+	return &Issuer{
+		BaseDifficulty: baseDifficulty,
+		MaxDifficulty:  max(baseDifficulty, maxDifficulty),
+		Threshold:      max(threshold, 1),
+		TTL:            5 * time.Minute,
+		key:            key,
+		store:          store,
+	}
+}
+
+// Issue returns a new challenge at the current difficulty.
+func (i *Issuer) Issue() (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+
+	now := time.Now()
+	difficulty := i.countIssued(now)
+	expiresAt := now.Add(i.TTL)
+
+	payload := fmt.Sprintf("%s.%d.%d", base64.RawURLEncoding.EncodeToString(nonce), difficulty, expiresAt.Unix())
+	return Challenge{
+		Challenge:  payload + "." + i.sign(payload),
+		Difficulty: difficulty,
+		Algorithm:  "sha256",
+		ExpiresAt:  expiresAt.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// countIssued records a challenge issued now and returns the difficulty for it.
+func (i *Issuer) countIssued(now time.Time) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	second := now.Unix()
+	bucket := &i.issued[second%int64(len(i.issued))]
+	if bucket.second != second {
+		bucket.second, bucket.count = second, 0
+	}
+	bucket.count++
+
+	volume := 0
+	for _, b := range i.issued {
+		if second-b.second < int64(len(i.issued)) {
+			volume += b.count
+		}
+	}
+
+	difficulty := i.BaseDifficulty
+	for v := i.Threshold; volume > v && difficulty < i.MaxDifficulty; v *= 2 {
+		difficulty++
+	}
+	return difficulty
+}
+
+// Verify checks a solution in the form "<challenge>:<counter>" and marks the challenge as used.
+func (i *Issuer) Verify(ctx context.Context, solution string) error {
+	challenge, counter, ok := strings.Cut(solution, ":")
+	if !ok || counter == "" || len(counter) > 20 {
+		return ErrMalformed
+	}
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return ErrMalformed
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(i.sign(payload))) {
+		return ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrInvalid
+	}
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	expiresAt := time.Unix(expiry, 0)
+	if time.Now().After(expiresAt) {
+		return ErrExpired
+	}
+
+	hash := sha256.Sum256([]byte(solution))
+	if leadingZeroBits(hash[:]) < difficulty {
+		return ErrWrong
+	}
+
+	fresh, err := i.store.Use(ctx, challenge, expiresAt)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrUsed
+	}
+	return nil
+}
+
+func (i *Issuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// MemoryStore keeps used challenges in memory, so they are only known to the process.
+type MemoryStore struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+	// Used challenges ordered by expiry, so that expired ones are dropped without a scan
+	expiry expiryHeap
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{used: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Use(ctx context.Context, challenge string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.expiry) > 0 && now.After(s.expiry[0].expiresAt) {
+		delete(s.used, heap.Pop(&s.expiry).(usedChallenge).challenge)
+	}
+	if _, ok := s.used[challenge]; ok {
+		return false, nil
+	}
+	s.used[challenge] = expiresAt
+	heap.Push(&s.expiry, usedChallenge{challenge: challenge, expiresAt: expiresAt})
+	return true, nil
+}
+
+type usedChallenge struct {
+	challenge string
+	expiresAt time.Time
+}
+
+// expiryHeap implements heap.Interface with the challenge that expires first on top.
+type expiryHeap []usedChallenge
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(usedChallenge)) }
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package pow
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve looks for a counter that meets the difficulty of the challenge.
+func solve(t *testing.T, c Challenge) string {
+	t.Helper()
+	for counter := 0; counter < 1<<24; counter++ {
+		solution := c.Challenge + ":" + strconv.Itoa(counter)
+		if hash := sha256.Sum256([]byte(solution)); leadingZeroBits(hash[:]) >= c.Difficulty {
+			return solution
+		}
+	}
+	t.Fatal("no solution found")
+	return ""
+}
+
+func newTestIssuer() *Issuer {
+	return NewIssuer([]byte("secret"), NewMemoryStore(), 4, 8, 2)
+}
+
+func TestIssuerRaisesDifficulty(t *testing.T) {
+	issuer := newTestIssuer()
+
+	// Difficulty grows by a bit every time the volume doubles past the threshold
+	want := []int{4, 4, 5, 5, 6, 6, 6, 6, 7, 7}
+	for n, difficulty := range want {
+		c, err := issuer.Issue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Difficulty != difficulty {
+			t.Errorf("challenge %d has difficulty %d, want %d", n+1, c.Difficulty, difficulty)
+		}
+	}
+	for n := 0; n < 100; n++ {
+		issuer.Issue()
+	}
+	if c, _ := issuer.Issue(); c.Difficulty != issuer.MaxDifficulty {
+		t.Errorf("difficulty = %d, want at most %d", c.Difficulty, issuer.MaxDifficulty)
+	}
+}
+
+func TestIssuerVerify(t *testing.T) {
+	issuer := newTestIssuer()
+	c, err := issuer.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := solve(t, c)
+
+	// Another issuer with a different key, as if the key wasn't configured
+	other := NewIssuer([]byte("other"), NewMemoryStore(), 4, 8, 2)
+
+	tests := []struct {
+		name     string
+		issuer   *Issuer
+		solution string
+		want     error
+	}{
+		{"missing counter", issuer, c.Challenge, ErrMalformed},
+		{"empty counter", issuer, c.Challenge + ":", ErrMalformed},
+		{"not a challenge", issuer, "abc:1", ErrMalformed},
+		{"forged difficulty", issuer, "x.0.9999999999.sig:1", ErrInvalid},
+		{"other key", other, solution, ErrInvalid},
+		{"valid", issuer, solution, nil},
+		{"replay", issuer, solution, ErrUsed},
+	}
+	for _, tt := range tests {
+		if err := tt.issuer.Verify(context.Background(), tt.solution); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestIssuerRejectsWrongSolution(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"), NewMemoryStore(), 16, 16, 100)
+	c, err := issuer.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for counter := 0; ; counter++ {
+		solution := c.Challenge + ":" + strconv.Itoa(counter)
+		if hash := sha256.Sum256([]byte(solution)); leadingZeroBits(hash[:]) < c.Difficulty {
+			if err := issuer.Verify(context.Background(), solution); err != ErrWrong {
+				t.Errorf("err = %v, want %v", err, ErrWrong)
+			}
+			return
+		}
+	}
+}
+
+func TestIssuerRejectsExpiredChallenge(t *testing.T) {
+	issuer := newTestIssuer()
+	issuer.TTL = -2 * time.Second
+	c, err := issuer.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := issuer.Verify(context.Background(), solve(t, c)); err != ErrExpired {
+		t.Errorf("err = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestIssuersShareKeyAndStore(t *testing.T) {
+	store := NewMemoryStore()
+	first := NewIssuer([]byte("secret"), store, 4, 8, 2)
+	// A restarted process, or another one, with the same key and store
+	second := NewIssuer([]byte("secret"), store, 4, 8, 2)
+
+	c, err := first.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := solve(t, c)
+	if err := second.Verify(context.Background(), solution); err != nil {
+		t.Fatalf("challenge of another issuer was refused: %s", err)
+	}
+	if err := first.Verify(context.Background(), solution); err != ErrUsed {
+		t.Errorf("challenge used with another issuer: err = %v, want %v", err, ErrUsed)
+	}
+}
+
+func TestMemoryStoreForgetsExpiredChallenges(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	store.Use(ctx, "old", now.Add(-time.Second))
+	store.Use(ctx, "older", now.Add(-time.Minute))
+	if ok, _ := store.Use(ctx, "new", now.Add(time.Minute)); !ok {
+		t.Fatal("new challenge was reported as used")
+	}
+	if len(store.used) != 1 || store.expiry.Len() != 1 {
+		t.Errorf("store keeps %d challenges, want only the one that didn't expire", len(store.used))
+	}
+	if ok, _ := store.Use(ctx, "new", now.Add(time.Minute)); ok {
+		t.Error("used challenge was accepted again")
+	}
+}
@@ -0,0 +1,151 @@
+// Package ratelimit limits how often clients may call routes using token buckets.
+//
+// Each bucket holds up to Limit.Requests tokens and refills at Requests per Period.
+// A request takes a token and is rejected when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Period, all of which can be made at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Rate returns how many tokens are added to the bucket per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimits parses limits of routes in the form "<pattern>=<requests>/<period>",
+// separated by semicolons, e.g. "POST /notes=30/1m; *=300/1m".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("limit %q is missing '='", entry)
+		}
+		pattern := strings.TrimSpace(entry[:i])
+		requests, period, ok := strings.Cut(strings.TrimSpace(entry[i+1:]), "/")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("limit %q must be in the form <pattern>=<requests>/<period>", entry)
+		}
+		n, err := strconv.Atoi(requests)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("limit %q has invalid number of requests", entry)
+		}
+		// Allow "1m" to be written as "m"
+		if period != "" && (period[0] < '0' || period[0] > '9') {
+			period = "1" + period
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("limit %q has invalid period", entry)
+		}
+		limits[pattern] = Limit{Requests: n, Period: d}
+	}
+	return limits, nil
+}
+
+// Result is the state of a bucket after a request.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests that can be made right away
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero if it is allowed now
+	RetryAfter time.Duration
+}
+
+// NewResult describes a bucket of the limit that has tokens left after a request.
+func NewResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.Rate()
+	result := Result{
+		Allowed:   allowed,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if tokens < 1 {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// Store keeps the buckets.
+type Store interface {
+	// Take takes a token from the bucket of the key if there is one.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// MemoryStore keeps buckets in memory, so limits apply per process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will be full again and can be forgotten
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		s.prune(now)
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate())
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	result := NewResult(limit, b.tokens, allowed)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// prune removes full buckets at most once a minute, so that the map doesn't grow without bound.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+	for key, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
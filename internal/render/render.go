@@ -0,0 +1,131 @@
+// Package render turns note messages into HTML that is safe to embed in a page.
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+// Renderer renders plain text and CommonMark to sanitized HTML and caches the output.
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// Least recently used entries are at the back
+	order *list.List
+}
+
+type entry struct {
+	key  string
+	html string
+}
+
+// New returns a renderer caching the output of up to cacheSize messages.
+func New(cacheSize int) *Renderer {
+	return &Renderer{
+		// Raw HTML in messages is left out by goldmark unless it is configured as unsafe
+		markdown: goldmark.New(),
+		policy:   newPolicy(),
+		size:     max(cacheSize, 0),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// newPolicy allows only the formatting CommonMark produces, with links to web and mail
+// addresses that don't pass on the referrer or ranking to the linked site.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "del", "code", "pre", "blockquote",
+		"ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6", "hr")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render returns the message as HTML. Messages are cached by key, which must change
+// whenever the message or its format do.
+func (r *Renderer) Render(key, message string, markdown bool) (string, error) {
+	if out, ok := r.get(key); ok {
+		return out, nil
+	}
+
+	var out string
+	if markdown {
+		var buf bytes.Buffer
+		if err := r.markdown.Convert([]byte(message), &buf); err != nil {
+			return "", err
+		}
+		out = r.policy.Sanitize(buf.String())
+	} else {
+		out = plain(message)
+	}
+
+	r.put(key, out)
+	return out, nil
+}
+
+// plain escapes the text and keeps its paragraphs and line breaks.
+func plain(message string) string {
+	message = strings.ReplaceAll(strings.TrimSpace(message), "\r\n", "\n")
+	if message == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, paragraph := range strings.Split(message, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i := range lines {
+			lines[i] = html.EscapeString(lines[i])
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>\n")
+	}
+	return b.String()
+}
+
+func (r *Renderer) get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[key]
+	if !ok {
+		return "", false
+	}
+	r.order.MoveToFront(e)
+	return e.Value.(*entry).html, true
+}
+
+func (r *Renderer) put(key, html string) {
+	if r.size == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.entries[key]; ok {
+		e.Value.(*entry).html = html
+		r.order.MoveToFront(e)
+		return
+	}
+	r.entries[key] = r.order.PushFront(&entry{key: key, html: html})
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*entry).key)
+	}
+}
@@ -0,0 +1,136 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		markdown bool
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "plain text is escaped",
+			message:  `<script>alert(1)</script> & "quotes"`,
+			want:     []string{"<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; &#34;quotes&#34;</p>"},
+			unwanted: []string{"<script>"},
+		},
+		{
+			name:    "plain text keeps paragraphs and line breaks",
+			message: "first\r\nline\n\n\n<b>second</b>",
+			want:    []string{"<p>first<br>\nline</p>\n<p>&lt;b&gt;second&lt;/b&gt;</p>\n"},
+		},
+		{
+			name:     "plain markdown isn't rendered",
+			message:  "**bold** [link](https://example.com)",
+			want:     []string{"**bold** [link](https://example.com)"},
+			unwanted: []string{"<strong>", "<a "},
+		},
+		{
+			name:     "markdown formatting",
+			message:  "**bold** and *em*\n\n1. one\n2. two",
+			markdown: true,
+			want:     []string{"<strong>bold</strong>", "<em>em</em>", "<ol>", "<li>one</li>"},
+		},
+		{
+			name:     "raw script in markdown",
+			message:  "Hi <script>alert(1)</script>\n\n<script>alert(2)</script>",
+			markdown: true,
+			unwanted: []string{"<script", "alert(2)"},
+		},
+		{
+			name:     "raw image with onerror in markdown",
+			message:  `<img src="x" onerror="alert(1)">`,
+			markdown: true,
+			unwanted: []string{"<img", "onerror"},
+		},
+		{
+			name:     "markdown image",
+			message:  `![x](https://example.com/x.png "title")`,
+			markdown: true,
+			unwanted: []string{"<img"},
+		},
+		{
+			name:     "javascript link",
+			message:  "[click](javascript:alert(1))",
+			markdown: true,
+			unwanted: []string{"javascript:", "href"},
+		},
+		{
+			name:     "javascript link with mixed case and entities",
+			message:  "[click](JaVaScRiPt&#58;alert(1))",
+			markdown: true,
+			unwanted: []string{"alert", "href"},
+		},
+		{
+			name:     "data link",
+			message:  "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+			markdown: true,
+			unwanted: []string{"data:", "href"},
+		},
+		{
+			name:     "web link",
+			message:  "[site](https://example.com/page)",
+			markdown: true,
+			want:     []string{`href="https://example.com/page"`, `rel="nofollow noreferrer noopener"`, `target="_blank"`},
+		},
+		{
+			name:     "autolink",
+			message:  "<https://example.com>",
+			markdown: true,
+			want:     []string{`href="https://example.com"`, `rel="nofollow noreferrer noopener"`},
+		},
+		{
+			name:     "mail link",
+			message:  "[mail](mailto:ann@example.com)",
+			markdown: true,
+			want:     []string{`href="mailto:ann@example.com"`, `rel="nofollow noreferrer"`},
+		},
+	}
+
+	r := New(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := r.Render(tt.name, tt.message, tt.markdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output %q doesn't contain %q", out, want)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(strings.ToLower(out), strings.ToLower(unwanted)) {
+					t.Errorf("output %q contains %q", out, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCachesByKey(t *testing.T) {
+	r := New(2)
+
+	first, _ := r.Render("note:1", "**first**", true)
+	if out, _ := r.Render("note:1", "**edited**", true); out != first {
+		t.Errorf("same key rendered %q, want the cached %q", out, first)
+	}
+	// An edit bumps the revision in the key
+	if out, _ := r.Render("note:2", "**edited**", true); !strings.Contains(out, "edited") {
+		t.Errorf("new key rendered stale %q", out)
+	}
+	// A changed format changes the key as well
+	if out, _ := r.Render("note:3", "**edited**", false); out != "<p>**edited**</p>\n" {
+		t.Errorf("new key rendered %q in the old format", out)
+	}
+
+	// The least recently used entry is evicted
+	if out, _ := r.Render("note:1", "evicted", false); out != "<p>evicted</p>\n" {
+		t.Errorf("evicted entry was served from the cache: %q", out)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -51,6 +52,16 @@ func ParseEnv(filename string) error {
 	return nil
 }
 
+// EnvInt returns the environmental variable parsed as an integer
+// or fallback if the variable isn't set or isn't a valid integer.
+func EnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
 // purifyString is a helper function that trims leading and trailing whitespace and removes quotation marks from a given string.
 func purifyString(s string) string {
 	s = strings.Trim(s, " ")
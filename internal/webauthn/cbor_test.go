@@ -0,0 +1,74 @@
+package webauthn
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/webauthn/webauthntest"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	var e webauthntest.Encoder
+	e.MapHeader(4)
+	e.Int(1)
+	e.Int(-7)
+	e.Text("bytes")
+	e.Bytes(bytes.Repeat([]byte{0xab}, 300))
+	e.Text("list")
+	e.ArrayHeader(2)
+	e.Int(70000)
+	e.Text("x")
+	e.Int(-300)
+	e.Data = append(e.Data, 0xf5) // true
+	data := append(e.Data, 0x01)  // trailing item
+
+	decoded, rest, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[interface{}]interface{}{
+		int64(1):    int64(-7),
+		"bytes":     bytes.Repeat([]byte{0xab}, 300),
+		"list":      []interface{}{int64(70000), "x"},
+		int64(-300): true,
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decoded %#v, want %#v", decoded, want)
+	}
+	if !bytes.Equal(rest, []byte{0x01}) {
+		t.Errorf("rest = %x, want 01", rest)
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated byte string", []byte{0x45, 0x01, 0x02}},
+		{"byte string longer than data", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"array longer than data", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"map longer than data", []byte{0xba, 0xff, 0xff, 0xff, 0xff}},
+		{"truncated map value", []byte{0xa1, 0x01}},
+		{"integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"negative integer overflow", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"indefinite length", []byte{0x5f, 0x41, 0x00, 0xff}},
+		{"tag", []byte{0xc0, 0x00}},
+		{"float", []byte{0xf9, 0x00, 0x00}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x00}},
+		{"nesting too deep", deep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); err == nil {
+				t.Error("malformed CBOR was decoded")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) accepted for credentials
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in order of preference.
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1
+	coseX   = -2
+	coseY   = -3
+	coseN   = -1
+	coseE   = -2
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+var errInvalidSignature = errors.New("signature is invalid")
+
+// verifySignature checks the signature over data with a COSE-encoded public key.
+func verifySignature(coseKey, data, signature []byte) error {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return err
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return errors.New("public key is not a COSE key")
+	}
+
+	alg, _ := key[int64(coseAlg)].(int64)
+	kty, _ := key[int64(coseKty)].(int64)
+
+	switch {
+	case alg == AlgES256 && kty == coseKtyEC2:
+		crv, _ := key[int64(coseCrv)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		y, _ := key[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return errors.New("invalid EC2 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return errors.New("invalid EC2 key")
+		}
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+			return errInvalidSignature
+		}
+		return nil
+
+	case alg == AlgEdDSA && kty == coseKtyOKP:
+		crv, _ := key[int64(coseCrv)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return errors.New("invalid OKP key")
+		}
+		if !ed25519.Verify(ed25519.PublicKey(x), data, signature) {
+			return errInvalidSignature
+		}
+		return nil
+
+	case alg == AlgRS256 && kty == coseKtyRSA:
+		n, _ := key[int64(coseN)].([]byte)
+		e, _ := key[int64(coseE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return errInvalidSignature
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+	}
+}
@@ -0,0 +1,203 @@
+// Package webauthn implements the relying party side of WebAuthn registration
+// and authentication ceremonies for passkeys.
+//
+// Attestation statements are not verified: the relying party asks for "none"
+// attestation and trusts the authenticator only to hold the private key.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// RelyingParty is the website passkeys are scoped to.
+type RelyingParty struct {
+	// ID is the domain passkeys are bound to, e.g. "annynotes.com"
+	ID   string
+	Name string
+	// Origins are the allowed origins of the frontend, e.g. "https://annynotes.com"
+	Origins []string
+}
+
+// Credential is a public key credential created during registration.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+}
+
+// NewChallenge returns a random challenge for a ceremony, encoded as base64url.
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// verifyClientData checks the type, challenge and origin of collected client data.
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("malformed client data: %w", err)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("unexpected ceremony type %q", data.Type)
+	}
+	if data.Challenge != challenge {
+		return errors.New("challenge doesn't match")
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return fmt.Errorf("origin %q is not allowed", data.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags      byte
+	signCount  uint32
+	credential *Credential
+}
+
+// parseAuthenticatorData parses authenticator data and checks that it was made
+// for this relying party with the user present.
+func (rp *RelyingParty) parseAuthenticatorData(data []byte, requireUserVerification bool) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data[:32], rpIDHash[:]) {
+		return nil, errors.New("credential was created for another relying party")
+	}
+
+	ad := &authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return nil, errors.New("user was not present")
+	}
+	if requireUserVerification && ad.flags&flagUserVerified == 0 {
+		return nil, errors.New("user was not verified")
+	}
+
+	if ad.flags&flagAttestedCredData != 0 {
+		rest := data[37:]
+		// AAGUID (16 bytes) and credential ID length (2 bytes)
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength > 1023 || len(rest) < idLength {
+			return nil, errors.New("invalid credential ID length")
+		}
+		credentialID := rest[:idLength]
+		rest = rest[idLength:]
+
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("malformed credential public key: %w", err)
+		}
+		ad.credential = &Credential{
+			ID:        bytes.Clone(credentialID),
+			PublicKey: bytes.Clone(rest[:len(rest)-len(afterKey)]),
+			SignCount: ad.signCount,
+		}
+	}
+
+	return ad, nil
+}
+
+// VerifyRegistration verifies the response of navigator.credentials.create()
+// and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte, requireUserVerification bool) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("malformed attestation object: %w", err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("malformed attestation object")
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object has no authenticator data")
+	}
+
+	ad, err := rp.parseAuthenticatorData(authData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if ad.credential == nil {
+		return nil, errors.New("attestation has no credential")
+	}
+
+	// Make sure the key is usable before storing it
+	if err := checkPublicKey(ad.credential.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return ad.credential, nil
+}
+
+// VerifyAssertion verifies the response of navigator.credentials.get() against the stored credential
+// and returns the new signature counter.
+func (rp *RelyingParty) VerifyAssertion(challenge string, credential Credential, clientDataJSON, authenticatorData, signature []byte, requireUserVerification bool) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	ad, err := rp.parseAuthenticatorData(authenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(bytes.Clone(authenticatorData), clientDataHash[:]...)
+	if err := verifySignature(credential.PublicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that doesn't increase means the authenticator may have been cloned.
+	// Authenticators that don't implement counters always report zero.
+	if (ad.signCount != 0 || credential.SignCount != 0) && ad.signCount <= credential.SignCount {
+		return 0, errors.New("signature counter didn't increase, the authenticator may be cloned")
+	}
+
+	return ad.signCount, nil
+}
+
+// checkPublicKey returns an error if the COSE key is not of a supported type.
+func checkPublicKey(coseKey []byte) error {
+	err := verifySignature(coseKey, nil, nil)
+	if err != nil && !errors.Is(err, errInvalidSignature) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/webauthn/webauthntest"
+)
+
+const testOrigin = "https://notes.example"
+
+func newTestRelyingParty() *RelyingParty {
+	return &RelyingParty{ID: "notes.example", Name: "Annynotes", Origins: []string{testOrigin}}
+}
+
+func newTestAuthenticator(t *testing.T, rp *RelyingParty) *webauthntest.Authenticator {
+	t.Helper()
+	a, err := webauthntest.New(rp.ID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// register creates a credential with the authenticator and returns it as stored by the relying party.
+func register(t *testing.T, rp *RelyingParty, a *webauthntest.Authenticator) Credential {
+	t.Helper()
+	clientData, attestation := a.Register("challenge")
+	credential, err := rp.VerifyRegistration("challenge", clientData, attestation, true)
+	if err != nil {
+		t.Fatalf("registration failed: %s", err)
+	}
+	return *credential
+}
+
+func TestRegistration(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+
+	credential := register(t, rp, a)
+	if !bytes.Equal(credential.ID, a.CredentialID) {
+		t.Errorf("credential ID = %x, want %x", credential.ID, a.CredentialID)
+	}
+	if !bytes.Equal(credential.PublicKey, a.PublicKey()) {
+		t.Error("public key doesn't match the one of the authenticator")
+	}
+}
+
+func TestRegistrationRejects(t *testing.T) {
+	rp := newTestRelyingParty()
+
+	tests := []struct {
+		name   string
+		change func(a *webauthntest.Authenticator)
+		// challenge the relying party expects
+		challenge string
+	}{
+		{"wrong challenge", func(*webauthntest.Authenticator) {}, "another-challenge"},
+		{"wrong origin", func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example" }, "challenge"},
+		{"wrong relying party", func(a *webauthntest.Authenticator) { a.RPID = "evil.example" }, "challenge"},
+		{"user not present", func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserVerified }, "challenge"},
+		{"user not verified", func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserPresent }, "challenge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t, rp)
+			tt.change(a)
+			clientData, attestation := a.Register("challenge")
+			if _, err := rp.VerifyRegistration(tt.challenge, clientData, attestation, true); err == nil {
+				t.Error("registration was accepted")
+			}
+		})
+	}
+}
+
+func TestRegistrationRejectsAssertionClientData(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	_, attestation := a.Register("challenge")
+	clientData := webauthntest.ClientData("webauthn.get", "challenge", testOrigin)
+	if _, err := rp.VerifyRegistration("challenge", clientData, attestation, true); err == nil {
+		t.Error("registration with client data of an assertion was accepted")
+	}
+}
+
+func TestRegistrationRejectsMalformedAttestation(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	clientData, attestation := a.Register("challenge")
+
+	var noAuthData webauthntest.Encoder
+	noAuthData.MapHeader(1)
+	noAuthData.Text("fmt")
+	noAuthData.Text("none")
+
+	// Authenticator data that claims a credential, but is cut off in its public key
+	truncatedKey := webauthntest.Encoder{}
+	authData := webauthntest.AuthenticatorData(rp.ID, webauthntest.FlagUserPresent|webauthntest.FlagUserVerified|webauthntest.FlagAttestedCredData, 0,
+		append(append(make([]byte, 16), 0, 1, 0xaa), a.PublicKey()[:10]...))
+	truncatedKey.MapHeader(1)
+	truncatedKey.Text("authData")
+	truncatedKey.Bytes(authData)
+
+	// Authenticator data without attested credential data
+	noCredential := webauthntest.Encoder{}
+	noCredential.MapHeader(1)
+	noCredential.Text("authData")
+	noCredential.Bytes(webauthntest.AuthenticatorData(rp.ID, webauthntest.FlagUserPresent|webauthntest.FlagUserVerified, 0, nil))
+
+	// A key of an unsupported type
+	var unsupportedKey webauthntest.Encoder
+	unsupportedKey.MapHeader(2)
+	unsupportedKey.Int(1)
+	unsupportedKey.Int(4)
+	unsupportedKey.Int(3)
+	unsupportedKey.Int(-7)
+	unsupportedKeyAttestation := webauthntest.Encoder{}
+	unsupportedKeyAttestation.MapHeader(1)
+	unsupportedKeyAttestation.Text("authData")
+	unsupportedKeyAttestation.Bytes(webauthntest.AuthenticatorData(rp.ID, webauthntest.FlagUserPresent|webauthntest.FlagUserVerified|webauthntest.FlagAttestedCredData, 0,
+		append(append(make([]byte, 16), 0, 1, 0xaa), unsupportedKey.Data...)))
+
+	tests := []struct {
+		name        string
+		attestation []byte
+	}{
+		{"empty", nil},
+		{"truncated", attestation[:len(attestation)/2]},
+		{"not a map", []byte{0x83, 0x01, 0x02, 0x03}},
+		{"indefinite length", []byte{0xbf, 0xff}},
+		{"no authenticator data", noAuthData.Data},
+		{"truncated public key", truncatedKey.Data},
+		{"no credential", noCredential.Data},
+		{"unsupported key", unsupportedKeyAttestation.Data},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rp.VerifyRegistration("challenge", clientData, tt.attestation, true); err == nil {
+				t.Error("malformed attestation was accepted")
+			}
+		})
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	credential := register(t, rp, a)
+
+	for i := 0; i < 2; i++ {
+		clientData, authData, signature := a.Login("login-challenge")
+		signCount, err := rp.VerifyAssertion("login-challenge", credential, clientData, authData, signature, true)
+		if err != nil {
+			t.Fatalf("assertion failed: %s", err)
+		}
+		if signCount != a.SignCount {
+			t.Errorf("sign count = %d, want %d", signCount, a.SignCount)
+		}
+		credential.SignCount = signCount
+	}
+}
+
+func TestAssertionRejectsCounterRegression(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	credential := register(t, rp, a)
+	credential.SignCount = 5
+
+	// A clone of the authenticator lags behind the counter of the original
+	a.SignCount = 4
+	clientData, authData, signature := a.Login("challenge")
+	if _, err := rp.VerifyAssertion("challenge", credential, clientData, authData, signature, true); err == nil {
+		t.Error("assertion with a counter that didn't increase was accepted")
+	}
+}
+
+func TestAssertionAllowsAuthenticatorsWithoutCounter(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	a.CounterDisabled = true
+	credential := register(t, rp, a)
+
+	for i := 0; i < 2; i++ {
+		clientData, authData, signature := a.Login("challenge")
+		if _, err := rp.VerifyAssertion("challenge", credential, clientData, authData, signature, true); err != nil {
+			t.Fatalf("assertion failed: %s", err)
+		}
+	}
+}
+
+func TestAssertionRejects(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	credential := register(t, rp, a)
+	other := newTestAuthenticator(t, rp)
+
+	tests := []struct {
+		name   string
+		assert func() (clientData, authData, signature []byte)
+	}{
+		{"wrong challenge", func() ([]byte, []byte, []byte) {
+			return a.Login("another-challenge")
+		}},
+		{"wrong origin", func() ([]byte, []byte, []byte) {
+			a.Origin = "https://evil.example"
+			defer func() { a.Origin = testOrigin }()
+			return a.Login("challenge")
+		}},
+		{"wrong relying party", func() ([]byte, []byte, []byte) {
+			a.RPID = "evil.example"
+			defer func() { a.RPID = rp.ID }()
+			return a.Login("challenge")
+		}},
+		{"user not verified", func() ([]byte, []byte, []byte) {
+			a.Flags = webauthntest.FlagUserPresent
+			defer func() { a.Flags = webauthntest.FlagUserPresent | webauthntest.FlagUserVerified }()
+			return a.Login("challenge")
+		}},
+		{"registration client data", func() ([]byte, []byte, []byte) {
+			clientData := webauthntest.ClientData("webauthn.create", "challenge", testOrigin)
+			authData := webauthntest.AuthenticatorData(rp.ID, webauthntest.FlagUserPresent|webauthntest.FlagUserVerified, 100, nil)
+			return clientData, authData, a.Sign(authData, clientData)
+		}},
+		{"signed by another key", func() ([]byte, []byte, []byte) {
+			other.SignCount = 100
+			return other.Login("challenge")
+		}},
+		{"tampered authenticator data", func() ([]byte, []byte, []byte) {
+			clientData, authData, signature := a.Login("challenge")
+			authData[36]++
+			return clientData, authData, signature
+		}},
+		{"tampered client data", func() ([]byte, []byte, []byte) {
+			_, authData, signature := a.Login("challenge")
+			return webauthntest.ClientData("webauthn.get", "challenge", testOrigin+"/"), authData, signature
+		}},
+		{"malformed signature", func() ([]byte, []byte, []byte) {
+			clientData, authData, _ := a.Login("challenge")
+			return clientData, authData, []byte{0x30, 0x01}
+		}},
+		{"short authenticator data", func() ([]byte, []byte, []byte) {
+			clientData, authData, signature := a.Login("challenge")
+			return clientData, authData[:36], signature
+		}},
+		{"malformed client data", func() ([]byte, []byte, []byte) {
+			_, authData, signature := a.Login("challenge")
+			return []byte("{"), authData, signature
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientData, authData, signature := tt.assert()
+			if _, err := rp.VerifyAssertion("challenge", credential, clientData, authData, signature, true); err == nil {
+				t.Error("assertion was accepted")
+			}
+		})
+	}
+}
+
+func TestAssertionRejectsMalformedPublicKey(t *testing.T) {
+	rp := newTestRelyingParty()
+	a := newTestAuthenticator(t, rp)
+	credential := register(t, rp, a)
+	credential.PublicKey = credential.PublicKey[:len(credential.PublicKey)-1]
+
+	clientData, authData, signature := a.Login("challenge")
+	if _, err := rp.VerifyAssertion("challenge", credential, clientData, authData, signature, true); err == nil {
+		t.Error("assertion with a truncated public key was accepted")
+	}
+}
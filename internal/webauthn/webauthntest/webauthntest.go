@@ -0,0 +1,198 @@
+// Package webauthntest provides a software authenticator for tests. It creates ES256
+// credentials with "none" attestation and signs assertions with them.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// Authenticator data flags
+const (
+	FlagUserPresent      = 0x01
+	FlagUserVerified     = 0x04
+	FlagAttestedCredData = 0x40
+)
+
+// Authenticator holds a single passkey for the relying party RPID.
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// Flags of authenticator data, user present and verified by default
+	Flags        byte
+	CredentialID []byte
+	Key          *ecdsa.PrivateKey
+	// SignCount is incremented before every assertion unless CounterDisabled is set
+	SignCount       uint32
+	CounterDisabled bool
+}
+
+// New returns an authenticator with a new credential for the relying party.
+func New(rpID, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        FlagUserPresent | FlagUserVerified,
+		CredentialID: id,
+		Key:          key,
+	}, nil
+}
+
+// PublicKey returns the COSE_Key of the credential.
+func (a *Authenticator) PublicKey() []byte {
+	var e Encoder
+	e.MapHeader(5)
+	e.Int(1) // kty
+	e.Int(2) // EC2
+	e.Int(3) // alg
+	e.Int(-7)
+	e.Int(-1) // crv
+	e.Int(1)  // P-256
+	e.Int(-2) // x
+	e.Bytes(a.Key.PublicKey.X.FillBytes(make([]byte, 32)))
+	e.Int(-3) // y
+	e.Bytes(a.Key.PublicKey.Y.FillBytes(make([]byte, 32)))
+	return e.Data
+}
+
+// ClientData returns collected client data of a ceremony.
+func ClientData(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+// AuthenticatorData returns authenticator data scoped to the relying party.
+func AuthenticatorData(rpID string, flags byte, signCount uint32, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+	return append(data, attestedCredentialData...)
+}
+
+// Register creates the credential as navigator.credentials.create() would,
+// returning client data and the attestation object.
+func (a *Authenticator) Register(challenge string) (clientDataJSON, attestationObject []byte) {
+	// AAGUID of zeros, credential ID and public key
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.CredentialID)))
+	attested = append(attested, a.CredentialID...)
+	attested = append(attested, a.PublicKey()...)
+
+	authData := AuthenticatorData(a.RPID, a.Flags|FlagAttestedCredData, a.SignCount, attested)
+
+	var e Encoder
+	e.MapHeader(3)
+	e.Text("fmt")
+	e.Text("none")
+	e.Text("attStmt")
+	e.MapHeader(0)
+	e.Text("authData")
+	e.Bytes(authData)
+
+	return ClientData("webauthn.create", challenge, a.Origin), e.Data
+}
+
+// Login signs an assertion as navigator.credentials.get() would,
+// returning client data, authenticator data and the signature.
+func (a *Authenticator) Login(challenge string) (clientDataJSON, authenticatorData, signature []byte) {
+	if !a.CounterDisabled {
+		a.SignCount++
+	}
+	clientDataJSON = ClientData("webauthn.get", challenge, a.Origin)
+	authenticatorData = AuthenticatorData(a.RPID, a.Flags, a.SignCount, nil)
+	return clientDataJSON, authenticatorData, a.Sign(authenticatorData, clientDataJSON)
+}
+
+// Sign returns the signature of an assertion over authenticator data and client data.
+func (a *Authenticator) Sign(authenticatorData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signature
+}
+
+// CredentialJSON returns the credential as serialized by PublicKeyCredential.toJSON().
+func CredentialJSON(id []byte, response map[string][]byte) json.RawMessage {
+	encoded := make(map[string]string, len(response))
+	for k, v := range response {
+		encoded[k] = base64.RawURLEncoding.EncodeToString(v)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"id":       base64.RawURLEncoding.EncodeToString(id),
+		"rawId":    base64.RawURLEncoding.EncodeToString(id),
+		"type":     "public-key",
+		"response": encoded,
+	})
+	return data
+}
+
+// Encoder writes CBOR items (RFC 8949) in the order they are added.
+type Encoder struct {
+	Data []byte
+}
+
+// Header writes the header of an item of the major type with the argument.
+func (e *Encoder) Header(major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		e.Data = append(e.Data, major|byte(arg))
+	case arg <= 0xff:
+		e.Data = append(e.Data, major|24, byte(arg))
+	case arg <= 0xffff:
+		e.Data = binary.BigEndian.AppendUint16(append(e.Data, major|25), uint16(arg))
+	case arg <= 0xffffffff:
+		e.Data = binary.BigEndian.AppendUint32(append(e.Data, major|26), uint32(arg))
+	default:
+		e.Data = binary.BigEndian.AppendUint64(append(e.Data, major|27), arg)
+	}
+}
+
+func (e *Encoder) Int(n int64) {
+	if n < 0 {
+		e.Header(1, uint64(-1-n))
+		return
+	}
+	e.Header(0, uint64(n))
+}
+
+func (e *Encoder) Bytes(b []byte) {
+	e.Header(2, uint64(len(b)))
+	e.Data = append(e.Data, b...)
+}
+
+func (e *Encoder) Text(s string) {
+	e.Header(3, uint64(len(s)))
+	e.Data = append(e.Data, s...)
+}
+
+// ArrayHeader starts an array of n items, which are added next.
+func (e *Encoder) ArrayHeader(n int) {
+	e.Header(4, uint64(n))
+}
+
+// MapHeader starts a map of n pairs, whose keys and values are added next.
+func (e *Encoder) MapHeader(n int) {
+	e.Header(5, uint64(n))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// Share links let the owner show a note, even a private one, to people without an account.
+// A password, if set, is sent in shareLinkPasswordHeader, so that it doesn't end up in logs.
+const shareLinkPasswordHeader = "X-Link-Password"
+
+// Longest lifetime of a share link
+const maxShareLinkTTL = 365 * 24 * time.Hour
+
+type shareLinkResponse struct {
+	ID          string `json:"id"`
+	NoteID      string `json:"note_id"`
+	Token       string `json:"token,omitempty"`
+	URL         string `json:"url,omitempty"`
+	HasPassword bool   `json:"has_password"`
+	MaxViews    *int64 `json:"max_views"`
+	Views       int64  `json:"views"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func newShareLinkResponse(link database.ShareLink) shareLinkResponse {
+	response := shareLinkResponse{
+		ID:          link.ID,
+		NoteID:      link.NoteID,
+		HasPassword: link.Password.Valid,
+		Views:       link.Views,
+		ExpiresAt:   link.ExpiresAt.String,
+		CreatedAt:   link.CreatedAt,
+	}
+	if link.MaxViews.Valid {
+		response.MaxViews = &link.MaxViews.Int64
+	}
+	return response
+}
+
+// createShareLinkHandler creates a link to the note. The token is only returned once.
+func (app *application) createShareLinkHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role != roleOwner {
+		log.Printf("Unauthorized attempt to create a share link to note %q by user %q", note.ID, user.ID)
+		http.Error(w, "Only the owner can share the note", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		// Seconds until the link expires
+		ExpiresIn *int64 `json:"expires_in"`
+		Password  string `json:"password"`
+		MaxViews  *int64 `json:"max_views"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	params := database.CreateShareLinkParams{
+		ID:     utils.GenerateUniqueId(),
+		NoteID: note.ID,
+	}
+
+	if body.ExpiresIn != nil {
+		// Check the seconds before converting, as large values would overflow the duration
+		if *body.ExpiresIn <= 0 || *body.ExpiresIn > int64(maxShareLinkTTL/time.Second) {
+			http.Error(w, "Link must expire within a year", http.StatusBadRequest)
+			return
+		}
+		ttl := time.Duration(*body.ExpiresIn) * time.Second
+		params.ExpiresAt = sql.NullString{String: time.Now().UTC().Add(ttl).Format(timestampFormat), Valid: true}
+	}
+
+	if body.MaxViews != nil {
+		if *body.MaxViews <= 0 {
+			http.Error(w, "View limit must be positive", http.StatusBadRequest)
+			return
+		}
+		params.MaxViews = sql.NullInt64{Int64: *body.MaxViews, Valid: true}
+	}
+
+	if body.Password != "" {
+		hashedPassword, err := auth.HashPassword(body.Password)
+		if err != nil {
+			log.Printf("Failed to hash password: %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		params.Password = sql.NullString{String: hashedPassword, Valid: true}
+	}
+
+	token, err := auth.GenerateShareLinkToken()
+	if err != nil {
+		log.Printf("Failed to generate share link token: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	params.TokenHash = auth.HashToken(token)
+
+	link, err := app.DB.CreateShareLink(r.Context(), params)
+	if err != nil {
+		log.Printf("Failed to create share link to note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %q created share link %q to note %q", user.ID, link.ID, note.ID)
+	response := newShareLinkResponse(link)
+	response.Token = token
+	response.URL = "/s/" + token
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (app *application) getShareLinksHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role != roleOwner {
+		http.Error(w, "Only the owner can see share links", http.StatusForbidden)
+		return
+	}
+
+	links, err := app.DB.FetchNoteShareLinks(r.Context(), note.ID)
+	if err != nil {
+		log.Printf("Failed to fetch share links to note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]shareLinkResponse, len(links))
+	for i, link := range links {
+		result[i] = newShareLinkResponse(link)
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total int                 `json:"total"`
+		Links []shareLinkResponse `json:"links"`
+	}{
+		Total: len(result),
+		Links: result,
+	})
+}
+
+func (app *application) revokeShareLinkHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role != roleOwner {
+		http.Error(w, "Only the owner can revoke share links", http.StatusForbidden)
+		return
+	}
+
+	linkID := r.PathValue("linkID")
+	revoked, err := app.DB.RevokeShareLink(r.Context(), database.RevokeShareLinkParams{
+		ID:     linkID,
+		NoteID: note.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to revoke share link %q: %s", linkID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		http.Error(w, "Link does not exist", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("User %q revoked share link %q", user.ID, linkID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSharedLinkNoteHandler serves the note behind a share link. Every successful request counts as a view.
+func (app *application) getSharedLinkNoteHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := app.DB.GetShareLinkByToken(r.Context(), auth.HashToken(r.PathValue("token")))
+	if err != nil {
+		http.Error(w, "Link does not exist", http.StatusNotFound)
+		return
+	}
+	if link.RevokedAt.Valid || (link.ExpiresAt.Valid && expired(link.ExpiresAt.String)) ||
+		(link.MaxViews.Valid && link.Views >= link.MaxViews.Int64) {
+		http.Error(w, "Link has expired", http.StatusGone)
+		return
+	}
+
+	if link.Password.Valid {
+		// Password guesses are throttled per link and per client, apart from logins
+		ip := clientIP(r)
+		wait := app.linkThrottle.Begin(link.ID)
+		if wait <= 0 {
+			if wait = app.linkIPThrottle.Begin(ip); wait > 0 {
+				app.linkThrottle.Done(link.ID)
+			}
+		}
+		if wait > 0 {
+			log.Printf("Attempt to open share link %q from %s while throttled", link.ID, ip)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+			http.Error(w, "Too many wrong passwords, try again later", http.StatusTooManyRequests)
+			return
+		}
+		defer app.linkThrottle.Done(link.ID)
+		defer app.linkIPThrottle.Done(ip)
+		password := r.Header.Get(shareLinkPasswordHeader)
+		if password == "" {
+			http.Error(w, "Link is protected by a password", http.StatusUnauthorized)
+			return
+		}
+		if !auth.CheckPassword(link.Password.String, password) {
+			app.linkThrottle.Fail(link.ID)
+			app.linkIPThrottle.Fail(ip)
+			http.Error(w, "Invalid password", http.StatusUnauthorized)
+			return
+		}
+		app.linkThrottle.Reset(link.ID)
+	}
+
+	note, err := app.DB.FetchNoteByID(r.Context(), link.NoteID)
+	if err != nil || note.Hidden != 0 {
+		http.Error(w, "Link does not exist", http.StatusNotFound)
+		return
+	}
+	// The note was replaced by a placeholder to keep its replies together
+	if note.Deleted != 0 {
+		http.Error(w, "Note was deleted", http.StatusGone)
+		return
+	}
+
+	// Count the view last, so that only served notes count against the limit
+	if _, err := app.DB.CountShareLinkView(r.Context(), link.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Link has expired", http.StatusGone)
+			return
+		}
+		log.Printf("Failed to count a view of share link %q: %s", link.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := app.noteResponses(r.Context(), []database.Note{note}, database.User{}, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions of note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, result[0])
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// createTestShareLink creates a share link with the settings in body and returns the response and the token.
+func createTestShareLink(t *testing.T, app *application, note database.Note, owner database.User, body string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/note/"+note.ID+"/links", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", note.ID)
+	w := httptest.NewRecorder()
+	app.createShareLinkHandler(w, r, owner)
+
+	var link shareLinkResponse
+	json.Unmarshal(w.Body.Bytes(), &link)
+	return w, link.Token
+}
+
+func openShareLink(app *application, token, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/s/"+token, nil)
+	r.SetPathValue("token", token)
+	if password != "" {
+		r.Header.Set(shareLinkPasswordHeader, password)
+	}
+	w := httptest.NewRecorder()
+	app.getSharedLinkNoteHandler(w, r)
+	return w
+}
+
+func TestShareLinkRejectsOverflowingExpiry(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "Hello", "")
+
+	// In nanoseconds, 18446744074 seconds wrap around to less than a second
+	for _, expiresIn := range []int64{18446744074, math.MaxInt64/int64(time.Second) + 1, -1, 0} {
+		w, _ := createTestShareLink(t, app, note, user, `{"expires_in":`+jsonNumber(expiresIn)+`}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expires_in %d responded with %d, want %d", expiresIn, w.Code, http.StatusBadRequest)
+		}
+	}
+	if w, _ := createTestShareLink(t, app, note, user, `{"expires_in":3600}`); w.Code != http.StatusCreated {
+		t.Errorf("valid expires_in responded with %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestShareLinkToDeletedNote(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "Hello", "")
+	_, token := createTestShareLink(t, app, note, user, `{}`)
+
+	if err := app.DB.SoftDeleteNote(context.Background(), note.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := openShareLink(app, token, ""); w.Code != http.StatusGone {
+		t.Errorf("link to a deleted note responded with %d, want %d", w.Code, http.StatusGone)
+	}
+}
+
+func TestShareLinkPasswordThrottle(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "Hello", "")
+	_, token := createTestShareLink(t, app, note, user, `{"password":"correct horse"}`)
+
+	throttled := false
+	for i := 0; i < 12 && !throttled; i++ {
+		w := openShareLink(app, token, "wrong")
+		throttled = w.Code == http.StatusTooManyRequests
+	}
+	if !throttled {
+		t.Error("wrong passwords weren't throttled")
+	}
+
+	// Guesses don't count against logins and aren't recorded as account lockouts
+	if wait := app.ipThrottle.Wait("192.0.2.1"); wait > 0 {
+		t.Errorf("login of the client is throttled for %s", wait)
+	}
+	var lockouts int
+	if err := app.conn.QueryRow(`SELECT count(*) FROM lockouts`).Scan(&lockouts); err != nil {
+		t.Fatal(err)
+	}
+	if lockouts != 0 {
+		t.Errorf("%d lockouts were recorded", lockouts)
+	}
+}
+
+func jsonNumber(n int64) string {
+	data, _ := json.Marshal(n)
+	return string(data)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentLoginGuessesAreThrottled(t *testing.T) {
+	app := newTestApp(t)
+	start := make(chan struct{})
+	var mu sync.Mutex
+	codes := make(map[int]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodPost, "/users/auth", strings.NewReader(`{"email":"ann@example.com","password":"guess"}`))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			<-start
+			app.authenticateUserHandler(w, r)
+			mu.Lock()
+			codes[w.Code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// The free attempts and the first delayed one, as if the guesses came one after another
+	if codes[http.StatusUnauthorized] != 4 || codes[http.StatusTooManyRequests] != 8 {
+		t.Errorf("responses = %v, want 4 guesses checked and 8 throttled", codes)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,15 +14,64 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/chtozamm/annynotes-go/internal/auth"
 	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/filter"
+	"github.com/chtozamm/annynotes-go/internal/oidc"
+	"github.com/chtozamm/annynotes-go/internal/pow"
+	"github.com/chtozamm/annynotes-go/internal/ratelimit"
+	"github.com/chtozamm/annynotes-go/internal/render"
 	"github.com/chtozamm/annynotes-go/internal/utils"
+	"github.com/chtozamm/annynotes-go/internal/webauthn"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // The core of the application
 type application struct {
-	DB  *database.Queries
-	srv *http.Server
+	DB *database.Queries
+	// Connection the queries run on, for transactions
+	conn *sql.DB
+	srv  *http.Server
+
+	// Failed login attempts per account and per client IP
+	accountThrottle *auth.LoginThrottle
+	ipThrottle      *auth.LoginThrottle
+	// Wrong passwords of share links per link and per client IP
+	linkThrottle   *auth.LoginThrottle
+	linkIPThrottle *auth.LoginThrottle
+
+	passwordPolicy auth.PasswordPolicy
+
+	// OpenID providers users can sign in with, by name
+	oidcProviders map[string]*oidc.Provider
+
+	// Website passkeys are registered for
+	relyingParty *webauthn.RelyingParty
+
+	// Number of open reports after which a note is hidden, zero disables hiding
+	reportHideThreshold int
+
+	// Filters notes go through on create and update
+	contentFilters filter.Pipeline
+	spamClassifier *filter.Bayes
+
+	// Proof-of-work challenges for signup and posting by unverified users, nil if disabled
+	pow *pow.Issuer
+
+	// Storage limits of users per role
+	quotas map[string]quota
+
+	// Mode of posting without an account, empty if disabled
+	anonymousPosting string
+	// How long authors of anonymous notes can edit them
+	editTokenTTL time.Duration
+
+	// Renders messages to HTML for clients that ask for it
+	renderer *render.Renderer
+
+	// Limits of requests per route pattern and the buckets counting them
+	rateLimits     map[string]ratelimit.Limit
+	rateLimitStore ratelimit.Store
 }
 
 func main() {
@@ -33,6 +85,18 @@ func main() {
 		log.Printf("Found PORT variable in .env: %s", port)
 	}
 
+	// Password hashing parameters and policy
+	hashParams, err := loadHashParams()
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth.SetHashParams(hashParams)
+	passwordPolicy := auth.PasswordPolicy{
+		MinLength:    utils.EnvInt("PASSWORD_MIN_LENGTH", auth.DefaultPasswordPolicy.MinLength),
+		MaxLength:    utils.EnvInt("PASSWORD_MAX_LENGTH", auth.DefaultPasswordPolicy.MaxLength),
+		RejectCommon: os.Getenv("PASSWORD_ALLOW_COMMON") != "true",
+	}
+
 	// Set a database file location depending on the OS
 	var localDataPath string
 	if runtime.GOOS == "windows" {
@@ -51,27 +115,123 @@ func main() {
 	r := http.NewServeMux()
 
 	app := application{
-		DB: database.New(connection),
+		DB:   database.New(connection),
+		conn: connection,
 		srv: &http.Server{
 			Addr:    port,
 			Handler: r,
 		},
+		accountThrottle: auth.NewLoginThrottle(3, 10, 15*time.Minute),
+		ipThrottle:      auth.NewLoginThrottle(20, 50, 15*time.Minute),
+		linkThrottle:    auth.NewLoginThrottle(3, 10, 15*time.Minute),
+		linkIPThrottle:  auth.NewLoginThrottle(20, 50, 15*time.Minute),
+		passwordPolicy:  passwordPolicy,
+		oidcProviders:   loadOIDCProviders(),
+		relyingParty:    loadRelyingParty(),
+
+		reportHideThreshold: utils.EnvInt("REPORT_HIDE_THRESHOLD", 3),
+		quotas:              loadQuotas(),
+		editTokenTTL:        time.Duration(utils.EnvInt("ANONYMOUS_EDIT_MINUTES", 30)) * time.Minute,
+		renderer:            render.New(utils.EnvInt("RENDER_CACHE_SIZE", 1000)),
+	}
+	app.loadModerators(context.Background())
+	app.loadContentFilters()
+	switch mode := os.Getenv("ANONYMOUS_POSTING"); mode {
+	case anonymousModerate, anonymousUnverified:
+		app.anonymousPosting = mode
+	case "":
+	default:
+		log.Fatalf("ANONYMOUS_POSTING must be %q or %q", anonymousModerate, anonymousUnverified)
 	}
+	app.loadRateLimits()
+	loadTrustedProxies()
+	app.loadProofOfWork()
 
 	// Router
-	r.HandleFunc("GET /notes", app.getNotesHandler)
-	r.HandleFunc("POST /notes", app.withAuth(app.createNoteHandler))
-	r.HandleFunc("GET /note/{id}", app.getNoteHandler)
-	r.HandleFunc("PATCH /note/{id}", app.withAuth(app.updateNoteHandler))
-	r.HandleFunc("DELETE /note/{id}", app.withAuth(app.deleteNoteHandler))
-	r.HandleFunc("GET /notes/{author}", app.getNotesFromAuthorHandler)
+	r.HandleFunc("GET /notes", app.withOptionalScope("notes:read", app.getNotesHandler))
+	r.HandleFunc("POST /notes", app.withOptionalScope("notes:write", app.createNoteHandler))
+	r.HandleFunc("GET /note/{id}", app.withOptionalScope("notes:read", app.getNoteHandler))
+	r.HandleFunc("PATCH /note/{id}", app.withOptionalScope("notes:write", app.updateNoteHandler))
+	r.HandleFunc("DELETE /note/{id}", app.withOptionalScope("notes:write", app.deleteNoteHandler))
+	r.HandleFunc("PUT /note/{id}/pin", app.withScope("notes:write", app.setPinned(true)))
+	r.HandleFunc("DELETE /note/{id}/pin", app.withScope("notes:write", app.setPinned(false)))
+	r.HandleFunc("PUT /note/{id}/favorite", app.withScope("notes:write", app.addFavoriteHandler))
+	r.HandleFunc("DELETE /note/{id}/favorite", app.withScope("notes:write", app.deleteFavoriteHandler))
+	r.HandleFunc("PUT /note/{id}/reactions/{emoji}", app.withScope("notes:write", app.setReaction(true)))
+	r.HandleFunc("DELETE /note/{id}/reactions/{emoji}", app.withScope("notes:write", app.setReaction(false)))
+	r.HandleFunc("GET /note/{id}/replies", app.withOptionalScope("notes:read", app.getRepliesHandler))
+	r.HandleFunc("GET /note/{id}/collaborators", app.withAuth(app.getCollaboratorsHandler))
+	r.HandleFunc("POST /note/{id}/collaborators", app.withAuth(app.addCollaboratorHandler))
+	r.HandleFunc("DELETE /note/{id}/collaborators/{username}", app.withAuth(app.removeCollaboratorHandler))
+	r.HandleFunc("POST /note/{id}/links", app.withAuth(app.createShareLinkHandler))
+	r.HandleFunc("GET /note/{id}/links", app.withAuth(app.getShareLinksHandler))
+	r.HandleFunc("DELETE /note/{id}/links/{linkID}", app.withAuth(app.revokeShareLinkHandler))
+	r.HandleFunc("GET /s/{token}", app.getSharedLinkNoteHandler)
+	r.HandleFunc("GET /notes/{author}", app.withOptionalScope("notes:read", app.getNotesFromAuthorHandler))
+	r.HandleFunc("POST /notes/move", app.withScope("notes:write", app.moveNotesHandler))
+	r.HandleFunc("GET /notebooks", app.withScope("notes:read", app.getNotebooksHandler))
+	r.HandleFunc("POST /notebooks", app.withScope("notes:write", app.createNotebookHandler))
+	r.HandleFunc("GET /notebooks/{id}", app.withScope("notes:read", app.getNotebookHandler))
+	r.HandleFunc("PATCH /notebooks/{id}", app.withScope("notes:write", app.updateNotebookHandler))
+	r.HandleFunc("DELETE /notebooks/{id}", app.withScope("notes:write", app.deleteNotebookHandler))
+	r.HandleFunc("GET /notebooks/{id}/notes", app.withScope("notes:read", app.getNotebookNotesHandler))
+	r.HandleFunc("GET /challenge", app.getChallengeHandler)
 	r.HandleFunc("POST /users", app.createUserHandler)
 	r.HandleFunc("POST /users/auth", app.authenticateUserHandler)
+	r.HandleFunc("POST /users/auth/2fa", app.verifyTwoFactorHandler)
+	r.HandleFunc("GET /users/auth/oidc/{provider}", app.startOIDCLoginHandler)
+	r.HandleFunc("GET /users/auth/oidc/{provider}/callback", app.oidcCallbackHandler)
+	r.HandleFunc("POST /users/auth/passkey/begin", app.beginPasskeyLoginHandler)
+	r.HandleFunc("POST /users/auth/passkey/finish", app.finishPasskeyLoginHandler)
+	r.HandleFunc("PUT /users/{username}/follow", app.withAuth(app.setFollowing(true)))
+	r.HandleFunc("DELETE /users/{username}/follow", app.withAuth(app.setFollowing(false)))
+	r.HandleFunc("GET /users/{username}/followers", app.getFollowsHandler(true))
+	r.HandleFunc("GET /users/{username}/following", app.getFollowsHandler(false))
+	r.HandleFunc("PUT /users/{username}/mute", app.withAuth(app.setRestriction(restrictionMute, true)))
+	r.HandleFunc("DELETE /users/{username}/mute", app.withAuth(app.setRestriction(restrictionMute, false)))
+	r.HandleFunc("PUT /users/{username}/block", app.withAuth(app.setRestriction(restrictionBlock, true)))
+	r.HandleFunc("DELETE /users/{username}/block", app.withAuth(app.setRestriction(restrictionBlock, false)))
+	r.HandleFunc("GET /users/me/mutes", app.withAuth(app.getRestrictedUsersHandler(restrictionMute)))
+	r.HandleFunc("GET /users/me/blocks", app.withAuth(app.getRestrictedUsersHandler(restrictionBlock)))
+	r.HandleFunc("POST /note/{id}/report", app.withAuth(app.reportNoteHandler))
+	r.HandleFunc("GET /moderation/reports", app.withModerator(app.getReportsHandler))
+	r.HandleFunc("POST /moderation/reports/{id}", app.withModerator(app.resolveReportHandler))
+	r.HandleFunc("POST /moderation/spam", app.withModerator(app.trainSpamHandler))
+	r.HandleFunc("GET /feed", app.withScope("notes:read", app.getFeedHandler))
+	r.HandleFunc("GET /users/me", app.withScope("profile", app.getCurrentUserHandler))
+	r.HandleFunc("GET /users/me/usage", app.withAuth(app.getUsageHandler))
+	r.HandleFunc("GET /users/me/favorites", app.withScope("notes:read", app.getFavoritesHandler))
+	r.HandleFunc("GET /users/me/notifications", app.withAuth(app.getNotificationsHandler))
+	r.HandleFunc("GET /users/me/notifications/unread", app.withAuth(app.getUnreadCountHandler))
+	r.HandleFunc("POST /users/me/notifications/read", app.withAuth(app.markAllNotificationsReadHandler))
+	r.HandleFunc("PUT /users/me/notifications/{id}/read", app.withAuth(app.setNotificationRead(true)))
+	r.HandleFunc("DELETE /users/me/notifications/{id}/read", app.withAuth(app.setNotificationRead(false)))
+	r.HandleFunc("GET /users/me/shared", app.withScope("notes:read", app.getSharedNotesHandler))
+	r.HandleFunc("GET /users/me/identities", app.withAuth(app.getIdentitiesHandler))
+	r.HandleFunc("GET /users/me/identities/{provider}/link", app.withAuth(app.startOIDCLinkHandler))
+	r.HandleFunc("POST /users/me/2fa/setup", app.withAuth(app.setupTwoFactorHandler))
+	r.HandleFunc("POST /users/me/2fa/confirm", app.withAuth(app.confirmTwoFactorHandler))
+	r.HandleFunc("DELETE /users/me/2fa", app.withAuth(app.disableTwoFactorHandler))
+	r.HandleFunc("POST /users/me/passkeys/begin", app.withAuth(app.beginPasskeyRegistrationHandler))
+	r.HandleFunc("POST /users/me/passkeys", app.withAuth(app.createPasskeyHandler))
+	r.HandleFunc("GET /users/me/passkeys", app.withAuth(app.getPasskeysHandler))
+	r.HandleFunc("DELETE /users/me/passkeys/{id}", app.withAuth(app.deletePasskeyHandler))
+	r.HandleFunc("GET /users/me/sessions", app.withAuth(app.getSessionsHandler))
+	r.HandleFunc("DELETE /users/me/sessions", app.withAuth(app.revokeAllSessionsHandler))
+	r.HandleFunc("DELETE /users/me/sessions/{id}", app.withAuth(app.revokeSessionHandler))
+	r.HandleFunc("POST /oauth/clients", app.withAuth(app.createOAuthClientHandler))
+	r.HandleFunc("GET /oauth/clients", app.withAuth(app.getOAuthClientsHandler))
+	r.HandleFunc("DELETE /oauth/clients/{id}", app.withAuth(app.deleteOAuthClientHandler))
+	r.HandleFunc("GET /oauth/authorize", app.withAuth(app.getAuthorizationHandler))
+	r.HandleFunc("POST /oauth/authorize", app.withAuth(app.authorizeHandler))
+	r.HandleFunc("POST /oauth/token", app.tokenHandler)
+	r.HandleFunc("POST /oauth/introspect", app.introspectHandler)
+	r.HandleFunc("POST /oauth/revoke", app.revokeHandler)
 
 	// Gracefully shut down by handling existing requests in the given time
 	go func() {
 		log.Print("Server is listening on localhost:", port)
-		http.ListenAndServe(":"+port, r)
+		http.ListenAndServe(":"+port, app.rateLimit(r))
 	}()
 	// Create a channel to listen for shutdown signals
 	quit := make(chan os.Signal, 1)
@@ -87,3 +247,30 @@ func main() {
 	}
 	log.Println("Server closed")
 }
+
+// loadHashParams reads the argon2 parameters from ARGON2_MEMORY (in KiB), ARGON2_ITERATIONS
+// and ARGON2_PARALLELISM. Values argon2 can't work with are refused rather than
+// making every login panic or wrapping around.
+func loadHashParams() (auth.HashParams, error) {
+	memory := utils.EnvInt("ARGON2_MEMORY", int(auth.DefaultHashParams.Memory))
+	iterations := utils.EnvInt("ARGON2_ITERATIONS", int(auth.DefaultHashParams.Iterations))
+	parallelism := utils.EnvInt("ARGON2_PARALLELISM", int(auth.DefaultHashParams.Parallelism))
+
+	switch {
+	case iterations < 1 || int64(iterations) > math.MaxUint32:
+		return auth.HashParams{}, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d", uint32(math.MaxUint32))
+	case parallelism < 1 || parallelism > math.MaxUint8:
+		return auth.HashParams{}, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d", math.MaxUint8)
+	// argon2 needs at least 8 KiB per thread
+	case memory < 8*parallelism || int64(memory) > math.MaxUint32:
+		return auth.HashParams{}, fmt.Errorf("ARGON2_MEMORY must be between %d (8 KiB per thread) and %d", 8*parallelism, uint32(math.MaxUint32))
+	}
+
+	return auth.HashParams{
+		Memory:      uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+		SaltLength:  auth.DefaultHashParams.SaltLength,
+		KeyLength:   auth.DefaultHashParams.KeyLength,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+)
+
+func TestLoadHashParams(t *testing.T) {
+	tests := []struct {
+		memory, iterations, parallelism string
+		valid                           bool
+	}{
+		{"", "", "", true},
+		{"19456", "2", "1", true},
+		{"65536", "3", "255", true},
+		{"", "0", "", false},
+		{"", "-1", "", false},
+		{"", "4294967296", "", false},
+		{"", "", "0", false},
+		{"", "", "256", false},
+		{"0", "", "", false},
+		{"15", "", "2", false},
+		{"4294967296", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("ARGON2_MEMORY", tt.memory)
+		t.Setenv("ARGON2_ITERATIONS", tt.iterations)
+		t.Setenv("ARGON2_PARALLELISM", tt.parallelism)
+
+		params, err := loadHashParams()
+		if (err == nil) != tt.valid {
+			t.Errorf("m=%q t=%q p=%q: err = %v, want valid = %t", tt.memory, tt.iterations, tt.parallelism, err, tt.valid)
+		}
+		if tt.valid && tt.memory == "" && params != auth.DefaultHashParams {
+			t.Errorf("params = %+v, want the defaults", params)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/chtozamm/annynotes-go/internal/auth"
@@ -10,17 +11,46 @@ import (
 type authedHandler func(http.ResponseWriter, *http.Request, database.User)
 
 func (app *application) withAuth(handler authedHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		claims, err := auth.ValidateJWT(r.Header)
+	return app.withScope("", handler)
+}
 
+// withScope is like withAuth, but also accepts access tokens issued to third-party apps
+// if they were granted the scope.
+func (app *application) withScope(scope string, handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, user, err := app.authenticate(r, scope)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			http.Error(w, err.msg, err.status)
+			return
+		}
+
+		handler(w, r, user)
+	}
+}
+
+// withModerator is like withAuth, but accepts only moderators.
+func (app *application) withModerator(handler authedHandler) http.HandlerFunc {
+	return app.withAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if !isModerator(user) {
+			http.Error(w, "Only moderators can access this resource", http.StatusForbidden)
+			return
+		}
+		handler(w, r, user)
+	})
+}
+
+// withOptionalScope is like withScope, but lets requests without credentials through
+// with an empty user. Requests with invalid credentials are still rejected.
+func (app *application) withOptionalScope(scope string, handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasCredentials(r) {
+			handler(w, r, database.User{})
 			return
 		}
 
-		user, err := app.DB.GetUserByEmail(r.Context(), claims.Email)
+		r, user, err := app.authenticate(r, scope)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			http.Error(w, err.msg, err.status)
 			return
 		}
 
@@ -28,6 +58,66 @@ func (app *application) withAuth(handler authedHandler) http.HandlerFunc {
 	}
 }
 
+// hasCredentials returns true if the request carries an access token in either the header or the cookie.
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	_, err := r.Cookie(auth.SessionCookieName)
+	return err == nil
+}
+
+var errSuspended = &authError{status: http.StatusForbidden, msg: "account is suspended"}
+
+type authError struct {
+	status int
+	msg    string
+}
+
+func (e *authError) Error() string {
+	return e.msg
+}
+
+// authenticate returns the user who made the request, along with the request carrying the
+// session ID in its context. Access tokens of third-party apps are accepted only if they
+// were granted the scope, so an empty scope accepts only tokens issued to our own clients.
+func (app *application) authenticate(r *http.Request, scope string) (*http.Request, database.User, *authError) {
+	if token := auth.BearerToken(r.Header); auth.IsOAuthAccessToken(token) {
+		user, err := app.userForOAuthToken(r.Context(), token, scope)
+		if err == nil && user.Suspended != 0 {
+			return r, database.User{}, errSuspended
+		}
+		return r, user, err
+	}
+
+	claims, fromCookie, err := auth.ValidateRequest(r)
+
+	if err != nil {
+		return r, database.User{}, &authError{status: http.StatusUnauthorized, msg: err.Error()}
+	}
+
+	user, err := app.DB.GetUserByEmail(r.Context(), claims.Email)
+	if err != nil {
+		return r, database.User{}, &authError{status: http.StatusNotFound, msg: http.StatusText(http.StatusNotFound)}
+	}
+	if user.Suspended != 0 {
+		return r, database.User{}, errSuspended
+	}
+
+	// Reject tokens of sessions that were logged out
+	if !app.checkSession(r.Context(), claims.SessionID, user.ID, clientIP(r)) {
+		return r, database.User{}, &authError{status: http.StatusUnauthorized, msg: "session has been revoked"}
+	}
+
+	// Browsers send cookies along with cross-site requests, so those must prove they come from our frontend
+	if fromCookie && !auth.CheckCSRF(r, claims.SessionID) {
+		return r, database.User{}, &authError{status: http.StatusForbidden, msg: "invalid CSRF token"}
+	}
+
+	ctx := context.WithValue(r.Context(), sessionIDKey{}, claims.SessionID)
+	return r.WithContext(ctx), user, nil
+}
+
 // func (app *application) logger(handler http.HandlerFunc) http.HandlerFunc {
 // 	return func(w http.ResponseWriter, r *http.Request) {
 // 		// logger.Logger.Info(r.Method, r.URL.Path, r.Header.Get("Referer"), r.RemoteAddr, r.Header.Get(""))
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Site-wide roles of users
+const (
+	userRoleUser      = "user"
+	userRoleModerator = "moderator"
+)
+
+// Status of a report
+const (
+	reportOpen      = "open"
+	reportDismissed = "dismissed"
+	reportResolved  = "resolved"
+)
+
+// Actions moderators can take on a report
+const (
+	// Dismiss closes the reports and restores the note if it was hidden automatically
+	actionDismiss = "dismiss"
+	// Hide hides the note from everyone but its owner
+	actionHide = "hide"
+	// Delete removes the note along with its replies
+	actionDelete = "delete"
+	// Suspend hides the note and suspends its author
+	actionSuspend = "suspend"
+)
+
+func validAction(action string) bool {
+	switch action {
+	case actionDismiss, actionHide, actionDelete, actionSuspend:
+		return true
+	}
+	return false
+}
+
+func validReportReason(reason string) bool {
+	switch reason {
+	case "spam", "harassment", "hate", "sexual", "violence", "other":
+		return true
+	}
+	return false
+}
+
+// loadModerators grants the moderator role to users listed by username in the MODERATORS variable.
+func (app *application) loadModerators(ctx context.Context) {
+	for _, username := range strings.Split(os.Getenv("MODERATORS"), ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		n, err := app.DB.SetUserRole(ctx, database.SetUserRoleParams{
+			Role:     userRoleModerator,
+			Username: username,
+		})
+		if err != nil {
+			log.Printf("Failed to make user %q a moderator: %s", username, err)
+			continue
+		}
+		if n == 0 {
+			log.Printf("Moderator %q is not a registered user", username)
+		}
+	}
+}
+
+func isModerator(user database.User) bool {
+	return user.Role == userRoleModerator
+}
+
+// reportNoteHandler flags a note for moderators. A user can report a note once, and the note
+// is hidden automatically once it gets enough open reports.
+func (app *application) reportNoteHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	note, role, ok := app.fetchNoteForUser(w, r, user)
+	if !ok {
+		return
+	}
+	if role == roleOwner {
+		http.Error(w, "You can't report your own note", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch {
+	case !validReportReason(body.Reason):
+		http.Error(w, "Reason must be one of spam, harassment, hate, sexual, violence or other", http.StatusBadRequest)
+		return
+	case len(body.Details) > 1000:
+		http.Error(w, "Details must be at most 1000 characters long", http.StatusBadRequest)
+		return
+	}
+
+	report, err := app.DB.CreateReport(r.Context(), database.CreateReportParams{
+		ID:         utils.GenerateUniqueId(),
+		NoteID:     note.ID,
+		ReporterID: user.ID,
+		Reason:     body.Reason,
+		Details:    strings.TrimSpace(body.Details),
+	})
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			http.Error(w, "You already reported the note", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to report note %q by user %q: %s", note.ID, user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if app.reportHideThreshold > 0 && note.Hidden == 0 {
+		reports, err := app.DB.CountOpenReports(r.Context(), note.ID)
+		if err == nil && reports >= int64(app.reportHideThreshold) {
+			err = app.DB.SetNoteHidden(r.Context(), database.SetNoteHiddenParams{Hidden: 1, ID: note.ID})
+			if err == nil {
+				log.Printf("Note %q was hidden after %d reports", note.ID, reports)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to check reports of note %q: %s", note.ID, err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusCreated, report)
+}
+
+type reportResponse struct {
+	database.Report
+	// Note is empty if the note was deleted
+	Note *database.Note `json:"note,omitempty"`
+}
+
+// getReportsHandler lists reports with the status from the URL query, open by default, oldest first.
+func (app *application) getReportsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportOpen
+	}
+	if status != reportOpen && status != reportDismissed && status != reportResolved {
+		http.Error(w, "Status must be one of open, dismissed or resolved", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePage(r, 50, 200)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := app.DB.CountReports(r.Context(), status)
+	if err != nil {
+		log.Printf("Failed to count reports: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	reports, err := app.DB.FetchReports(r.Context(), database.FetchReportsParams{
+		Status: status,
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		log.Printf("Failed to fetch reports: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]reportResponse, len(reports))
+	for i, report := range reports {
+		result[i].Report = report
+		if note, err := app.DB.FetchNoteByID(r.Context(), report.NoteID); err == nil {
+			result[i].Note = &note
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total   int64            `json:"total"`
+		Reports []reportResponse `json:"reports"`
+	}{
+		Total:   total,
+		Reports: result,
+	})
+}
+
+// resolveReportHandler applies a moderator action to the reported note and closes
+// all open reports of the note with the moderator's note recorded.
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	report, err := app.DB.FetchReportByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Report does not exist", http.StatusNotFound)
+		return
+	}
+	if report.Status != reportOpen {
+		http.Error(w, "Report is already closed", http.StatusConflict)
+		return
+	}
+
+	var body struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	err = decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+	if len(body.Note) > 1000 {
+		http.Error(w, "Note must be at most 1000 characters long", http.StatusBadRequest)
+		return
+	}
+
+	note, err := app.DB.FetchNoteByID(r.Context(), report.NoteID)
+	noteExists := err == nil
+
+	// Moderators' decisions on spam reports teach the spam classifier
+	if noteExists && report.Reason == "spam" && validAction(body.Action) {
+		if err := app.spamClassifier.Train(r.Context(), note.Message, body.Action != actionDismiss); err != nil {
+			log.Printf("Failed to train the spam classifier on note %q: %s", note.ID, err)
+		}
+	}
+
+	status := reportResolved
+	switch body.Action {
+	case actionDismiss:
+		status = reportDismissed
+		if noteExists && note.Hidden != 0 {
+			err = app.DB.SetNoteHidden(r.Context(), database.SetNoteHiddenParams{Hidden: 0, ID: note.ID})
+			if err == nil && note.UserID != "" {
+				// Mentioned users couldn't see the note while it was hidden
+				note.Hidden = 0
+				if author, err := app.DB.GetUserByID(r.Context(), note.UserID); err == nil {
+					app.updateMentions(r.Context(), note, author)
+				}
+			}
+		}
+	case actionHide:
+		if noteExists {
+			err = app.DB.SetNoteHidden(r.Context(), database.SetNoteHiddenParams{Hidden: 1, ID: note.ID})
+		}
+	case actionDelete:
+		if noteExists {
+			err = app.removeThread(r.Context(), note, true)
+		}
+	case actionSuspend:
+		if !noteExists {
+			http.Error(w, "Note does not exist", http.StatusNotFound)
+			return
+		}
+		err = app.DB.SetNoteHidden(r.Context(), database.SetNoteHiddenParams{Hidden: 1, ID: note.ID})
+		if err == nil {
+			err = app.DB.SetUserSuspended(r.Context(), database.SetUserSuspendedParams{Suspended: 1, ID: note.UserID})
+		}
+	default:
+		http.Error(w, "Action must be one of dismiss, hide, delete or suspend", http.StatusBadRequest)
+		return
+	}
+	if noteExists && err != nil {
+		log.Printf("Failed to %s note %q: %s", body.Action, note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.ResolveReports(r.Context(), database.ResolveReportsParams{
+		Status:        status,
+		Action:        body.Action,
+		ModeratorID:   user.ID,
+		ModeratorNote: strings.TrimSpace(body.Note),
+		NoteID:        report.NoteID,
+	})
+	if err != nil {
+		log.Printf("Failed to resolve reports of note %q: %s", report.NoteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Moderator %q took action %q on note %q", user.ID, body.Action, report.NoteID)
+	w.WriteHeader(http.StatusNoContent)
+}
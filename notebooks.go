@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// Most notes that can be moved with one request
+const maxBulkMove = 100
+
+// validNotebookFields checks the fields users can set on a notebook.
+func validNotebookFields(name, description string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("Name is required")
+	case len(name) > 100:
+		return errors.New("Name must be at most 100 characters long")
+	case len(description) > 1000:
+		return errors.New("Description must be at most 1000 characters long")
+	}
+	return nil
+}
+
+func (app *application) getNotebooksHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notebooks, err := app.DB.FetchNotebooks(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch notebooks of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if notebooks == nil {
+		notebooks = []database.Notebook{}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total     int                 `json:"total"`
+		Notebooks []database.Notebook `json:"notebooks"`
+	}{
+		Total:     len(notebooks),
+		Notebooks: notebooks,
+	})
+}
+
+func (app *application) createNotebookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Position    int64  `json:"position"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := validNotebookFields(body.Name, body.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := app.DB.CreateNotebook(r.Context(), database.CreateNotebookParams{
+		ID:          utils.GenerateUniqueId(),
+		UserID:      user.ID,
+		Name:        body.Name,
+		Description: body.Description,
+		Position:    body.Position,
+	})
+	if err != nil {
+		log.Printf("Failed to create a notebook: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("New notebook created with the ID %q", notebook.ID)
+	respondWithJSON(w, http.StatusCreated, notebook)
+}
+
+// fetchNotebook fetches the notebook from the path. Notebooks of other users are reported as missing.
+func (app *application) fetchNotebook(w http.ResponseWriter, r *http.Request, user database.User) (database.Notebook, bool) {
+	id := r.PathValue("id")
+
+	if !utils.ValidateId(id) {
+		http.Error(w, "Invalid notebook ID", http.StatusBadRequest)
+		return database.Notebook{}, false
+	}
+
+	notebook, err := app.DB.GetNotebook(r.Context(), database.GetNotebookParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		http.Error(w, "Notebook does not exist", http.StatusNotFound)
+		return database.Notebook{}, false
+	}
+	return notebook, true
+}
+
+func (app *application) getNotebookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notebook, ok := app.fetchNotebook(w, r, user)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, notebook)
+}
+
+func (app *application) updateNotebookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notebook, ok := app.fetchNotebook(w, r, user)
+	if !ok {
+		return
+	}
+
+	// Omitted fields are left unchanged
+	var body struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Position    *int64  `json:"position"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if body.Name != nil {
+		notebook.Name = *body.Name
+	}
+	if body.Description != nil {
+		notebook.Description = *body.Description
+	}
+	if body.Position != nil {
+		notebook.Position = *body.Position
+	}
+	if err := validNotebookFields(notebook.Name, notebook.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := app.DB.UpdateNotebook(r.Context(), database.UpdateNotebookParams{
+		Name:        notebook.Name,
+		Description: notebook.Description,
+		Position:    notebook.Position,
+		ID:          notebook.ID,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to update a notebook with the ID %q: %s", notebook.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Update a notebook with the ID %q", notebook.ID)
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// deleteNotebookHandler deletes the notebook. Its notes are kept and no longer belong to any notebook.
+func (app *application) deleteNotebookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	deleted, err := app.DB.DeleteNotebook(r.Context(), database.DeleteNotebookParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to delete a notebook with the ID %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Notebook does not exist", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Delete a notebook with the ID %q", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getNotebookNotesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	notebook, ok := app.fetchNotebook(w, r, user)
+	if !ok {
+		return
+	}
+
+	var notes []database.Note
+	var err error
+
+	// Fetch notes ordered according to the URL query
+	sortQuery := r.URL.Query().Get("sort")
+	switch strings.ToLower(sortQuery) {
+	case "desc":
+		notes, err = app.DB.FetchNotebookNotesDESC(r.Context(), database.FetchNotebookNotesDESCParams{
+			NotebookID: notebook.ID,
+			UserID:     user.ID,
+		})
+	default:
+		notes, err = app.DB.FetchNotebookNotes(r.Context(), database.FetchNotebookNotesParams{
+			NotebookID: notebook.ID,
+			UserID:     user.ID,
+		})
+	}
+
+	if err != nil {
+		log.Printf("Failed to fetch notes of notebook %q: %s", notebook.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if len(notes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	result, err := app.noteResponses(r.Context(), notes, user, wantsHTML(r))
+	if err != nil {
+		log.Printf("Failed to fetch reactions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total int            `json:"total"`
+		Notes []noteResponse `json:"notes"`
+	}{
+		Total: len(result),
+		Notes: result,
+	})
+}
+
+// moveNotesHandler moves notes of the user to a notebook, or out of any notebook
+// if the notebook ID is empty. Nothing is moved unless all notes belong to the user.
+func (app *application) moveNotesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		NoteIDs    []string `json:"note_ids"`
+		NotebookID string   `json:"notebook_id"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if len(body.NoteIDs) == 0 {
+		http.Error(w, "Note IDs are required", http.StatusBadRequest)
+		return
+	}
+	if len(body.NoteIDs) > maxBulkMove {
+		http.Error(w, "Too many notes to move at once", http.StatusBadRequest)
+		return
+	}
+
+	if body.NotebookID != "" && !app.ownsNotebook(r, user, body.NotebookID) {
+		http.Error(w, "Notebook does not exist", http.StatusNotFound)
+		return
+	}
+
+	for _, id := range body.NoteIDs {
+		note, err := app.DB.FetchNoteByID(r.Context(), id)
+		if err != nil || note.UserID != user.ID {
+			http.Error(w, "Note "+id+" does not exist", http.StatusNotFound)
+			return
+		}
+	}
+
+	for _, id := range body.NoteIDs {
+		err := app.DB.MoveNote(r.Context(), database.MoveNoteParams{
+			NotebookID: body.NotebookID,
+			ID:         id,
+			UserID:     user.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to move note %q to notebook %q: %s", id, body.NotebookID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	log.Printf("User %q moved %d notes to notebook %q", user.ID, len(body.NoteIDs), body.NotebookID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownsNotebook returns true if the notebook exists and belongs to the user.
+func (app *application) ownsNotebook(r *http.Request, user database.User, notebookID string) bool {
+	_, err := app.DB.GetNotebook(r.Context(), database.GetNotebookParams{
+		ID:     notebookID,
+		UserID: user.ID,
+	})
+	return err == nil
+}
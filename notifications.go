@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// Types of notifications
+const (
+	notificationMention  = "mention"
+	notificationReply    = "reply"
+	notificationReaction = "reaction"
+)
+
+// Most users a note can mention
+const maxMentions = 10
+
+// mentionPattern matches @username not preceded by a word character, so that emails are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w+(?:[.-]\w+)*)`)
+
+// parseMentions returns usernames mentioned in the message, without duplicates.
+func parseMentions(message string) []string {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		username := match[1]
+		if !slices.Contains(usernames, username) {
+			usernames = append(usernames, username)
+		}
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+// updateMentions stores users mentioned in the note and notifies those who weren't mentioned before.
+// Users who can't see the note or who blocked the author are not mentioned, so that they are notified
+// once the note is edited or unhidden and they can see it. Guests can't mention anyone.
+func (app *application) updateMentions(ctx context.Context, note database.Note, author database.User) {
+	if author.ID == "" {
+		return
+	}
+	previous, err := app.DB.FetchNoteMentions(ctx, note.ID)
+	if err != nil {
+		log.Printf("Failed to fetch mentions of note %q: %s", note.ID, err)
+		return
+	}
+
+	var mentioned []string
+	for _, username := range parseMentions(note.Message) {
+		user, err := app.DB.GetUserByUsername(ctx, username)
+		if err != nil || app.hasBlocked(ctx, user.ID, author.ID) || !app.canViewNote(ctx, note, user) {
+			continue
+		}
+		mentioned = append(mentioned, user.ID)
+
+		added, err := app.DB.AddMention(ctx, database.AddMentionParams{
+			NoteID: note.ID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to store mention of user %q in note %q: %s", user.ID, note.ID, err)
+			continue
+		}
+		if added > 0 {
+			app.notify(ctx, user.ID, author.ID, notificationMention, note.ID, "")
+		}
+	}
+
+	// Forget users who are no longer mentioned after an edit
+	for _, userID := range previous {
+		if slices.Contains(mentioned, userID) {
+			continue
+		}
+		err := app.DB.DeleteMention(ctx, database.DeleteMentionParams{
+			NoteID: note.ID,
+			UserID: userID,
+		})
+		if err != nil {
+			log.Printf("Failed to delete mention of user %q in note %q: %s", userID, note.ID, err)
+		}
+	}
+}
+
+// notify adds a notification to the inbox of the recipient. Users are not notified of their own actions,
+// of actions of users they blocked or of guests, who can't be blocked.
+// Failures are only logged, as notifications are not essential to the action that caused them.
+func (app *application) notify(ctx context.Context, recipientID, actorID, notificationType, noteID, emoji string) {
+	if recipientID == "" || actorID == "" || recipientID == actorID || app.hasBlocked(ctx, recipientID, actorID) {
+		return
+	}
+
+	err := app.DB.CreateNotification(ctx, database.CreateNotificationParams{
+		ID:      utils.GenerateUniqueId(),
+		UserID:  recipientID,
+		Type:    notificationType,
+		ActorID: actorID,
+		NoteID:  noteID,
+		Emoji:   emoji,
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %q of %s on note %q: %s", recipientID, notificationType, noteID, err)
+	}
+}
+
+type notificationActor struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type notificationResponse struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	NoteID    string            `json:"note_id"`
+	Emoji     string            `json:"emoji,omitempty"`
+	Actor     notificationActor `json:"actor"`
+	Read      bool              `json:"read"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// getNotificationsHandler lists notifications of the user, newest first. With ?unread=true only unread ones are listed.
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := parsePage(r, 20, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rows []database.FetchNotificationsRow
+	var total int64
+	if r.URL.Query().Get("unread") == "true" {
+		var unread []database.FetchUnreadNotificationsRow
+		unread, err = app.DB.FetchUnreadNotifications(r.Context(), database.FetchUnreadNotificationsParams{
+			UserID: user.ID,
+			Limit:  int64(limit),
+			Offset: int64(offset),
+		})
+		for _, row := range unread {
+			rows = append(rows, database.FetchNotificationsRow(row))
+		}
+		if err == nil {
+			total, err = app.DB.CountUnreadNotifications(r.Context(), user.ID)
+		}
+	} else {
+		rows, err = app.DB.FetchNotifications(r.Context(), database.FetchNotificationsParams{
+			UserID: user.ID,
+			Limit:  int64(limit),
+			Offset: int64(offset),
+		})
+		if err == nil {
+			total, err = app.DB.CountNotifications(r.Context(), user.ID)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to fetch notifications of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	unread, err := app.DB.CountUnreadNotifications(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	notifications := make([]notificationResponse, len(rows))
+	for i, row := range rows {
+		notifications[i] = notificationResponse{
+			ID:     row.ID,
+			Type:   row.Type,
+			NoteID: row.NoteID,
+			Emoji:  row.Emoji,
+			Actor: notificationActor{
+				ID:       row.ActorID,
+				Username: row.ActorUsername,
+				Name:     row.ActorName,
+			},
+			Read:      row.Read != 0,
+			CreatedAt: row.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total         int64                  `json:"total"`
+		Unread        int64                  `json:"unread"`
+		Notifications []notificationResponse `json:"notifications"`
+	}{
+		Total:         total,
+		Unread:        unread,
+		Notifications: notifications,
+	})
+}
+
+// getUnreadCountHandler responds with the number of unread notifications of the user.
+func (app *application) getUnreadCountHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	unread, err := app.DB.CountUnreadNotifications(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int64{"unread": unread})
+}
+
+// setNotificationRead returns a handler marking a notification as read or unread.
+func (app *application) setNotificationRead(read bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		id := r.PathValue("id")
+
+		var value int64
+		if read {
+			value = 1
+		}
+		updated, err := app.DB.SetNotificationRead(r.Context(), database.SetNotificationReadParams{
+			Read:   value,
+			ID:     id,
+			UserID: user.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to mark notification %q: %s", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if updated == 0 {
+			http.Error(w, "Notification does not exist", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// markAllNotificationsReadHandler marks every notification of the user as read.
+func (app *application) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := app.DB.MarkAllNotificationsRead(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to mark notifications of user %q as read: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
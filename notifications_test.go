@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+func countTestNotifications(t *testing.T, app *application, user database.User) int64 {
+	t.Helper()
+	count, err := app.DB.CountNotifications(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestMentionInPrivateNoteNotifiesOncePublished(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	bob := createTestUser(t, app, "bob", "bob@example.com")
+	note := createTestNote(t, app, ann, "Hello @bob", "")
+
+	note, err := app.DB.UpdateNote(ctx, database.UpdateNoteParams{
+		ID:         note.ID,
+		Author:     note.Author,
+		Message:    note.Message,
+		Visibility: visibilityPrivate,
+		Format:     note.Format,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.updateMentions(ctx, note, ann)
+	if count := countTestNotifications(t, app, bob); count != 0 {
+		t.Fatalf("user was notified %d times of a mention they can't see", count)
+	}
+
+	note, err = app.DB.UpdateNote(ctx, database.UpdateNoteParams{
+		ID:         note.ID,
+		Author:     note.Author,
+		Message:    note.Message,
+		Visibility: visibilityPublic,
+		Format:     note.Format,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.updateMentions(ctx, note, ann)
+	if count := countTestNotifications(t, app, bob); count != 1 {
+		t.Errorf("user was notified %d times once the note was published, want 1", count)
+	}
+}
+
+func TestMentionInHiddenNoteNotifiesOnceUnhidden(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	bob := createTestUser(t, app, "bob", "bob@example.com")
+	moderator := createTestUser(t, app, "mod", "mod@example.com")
+	moderator.Role = userRoleModerator
+	note := createTestNote(t, app, ann, "Hello @bob", "")
+
+	if err := app.flagNote(ctx, note.ID, "other", "Held for review"); err != nil {
+		t.Fatal(err)
+	}
+	note.Hidden = 1
+	app.updateMentions(ctx, note, ann)
+	if count := countTestNotifications(t, app, bob); count != 0 {
+		t.Fatalf("user was notified %d times of a mention in a hidden note", count)
+	}
+
+	resolveTestReport(t, app, moderator, actionDismiss)
+
+	if count := countTestNotifications(t, app, bob); count != 1 {
+		t.Errorf("user was notified %d times once the note was unhidden, want 1", count)
+	}
+}
@@ -0,0 +1,648 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// oauthError is an error response as defined by RFC 6749, section 5.2.
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
+}
+
+func respondWithOAuthError(w http.ResponseWriter, status int, code, description string) {
+	respondWithJSON(w, status, &oauthError{Error: code, Description: description})
+}
+
+type oauthClientResponse struct {
+	ID           string   `json:"client_id"`
+	Secret       string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Confidential bool     `json:"confidential"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+func newOAuthClientResponse(client database.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectUris),
+		Confidential: client.SecretHash != "",
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// validRedirectURI allows absolute https URIs, http URIs of the loopback interface
+// and private-use schemes of native apps (RFC 8252). Fragments are not allowed.
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || strings.ContainsAny(uri, " \t\n") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	case "javascript", "data", "file":
+		return false
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+func (app *application) createOAuthClientHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if len(body.Name) < 2 || len(body.Name) > 50 {
+		http.Error(w, "Name must be between 2 and 50 characters long", http.StatusBadRequest)
+		return
+	}
+	if len(body.RedirectURIs) == 0 {
+		http.Error(w, "At least one redirect URI is required", http.StatusBadRequest)
+		return
+	}
+	for _, uri := range body.RedirectURIs {
+		if !validRedirectURI(uri) {
+			http.Error(w, "Invalid redirect URI: "+uri, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Public clients, such as single-page and native apps, can't keep a secret and rely on PKCE alone
+	var secret, secretHash string
+	if body.Confidential {
+		secret, err = auth.GenerateOAuthClientSecret()
+		if err != nil {
+			log.Printf("Failed to generate client secret: %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		secretHash = auth.HashToken(secret)
+	}
+
+	client, err := app.DB.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		ID:           utils.GenerateUniqueId(),
+		Name:         body.Name,
+		SecretHash:   secretHash,
+		RedirectUris: strings.Join(body.RedirectURIs, " "),
+		UserID:       user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to create an OAuth client: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("New OAuth client created with the ID %q by user %q", client.ID, user.ID)
+	response := newOAuthClientResponse(client)
+	response.Secret = secret
+	respondWithJSON(w, http.StatusCreated, &response)
+}
+
+func (app *application) getOAuthClientsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	clients, err := app.DB.FetchUserOAuthClients(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch OAuth clients of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		result[i] = newOAuthClientResponse(client)
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total   int                   `json:"total"`
+		Clients []oauthClientResponse `json:"clients"`
+	}{
+		Total:   len(result),
+		Clients: result,
+	})
+}
+
+func (app *application) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	deleted, err := app.DB.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to delete OAuth client %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Client does not exist", http.StatusNotFound)
+		return
+	}
+
+	if err := app.DB.RevokeOAuthClientTokens(r.Context(), id); err != nil {
+		log.Printf("Failed to revoke tokens of OAuth client %q: %s", id, err)
+	}
+
+	log.Printf("Delete OAuth client %q", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest holds the parameters of an authorization request (RFC 6749, section 4.1.1).
+type authorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// errorRedirect returns the redirect URI carrying the error back to the client.
+func (req authorizationRequest) errorRedirect(code, description string) *oauthError {
+	query := url.Values{}
+	query.Set("error", code)
+	query.Set("error_description", description)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	return &oauthError{Error: code, Description: description, RedirectURI: withQuery(req.RedirectURI, query)}
+}
+
+func withQuery(uri string, query url.Values) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query.Encode()
+	}
+	return uri + "?" + query.Encode()
+}
+
+// validateAuthorizationRequest checks the client and the redirect URI first, as errors about them
+// must not be sent to the redirect URI. Other errors carry the redirect URI for the frontend to follow.
+func (app *application) validateAuthorizationRequest(ctx context.Context, req *authorizationRequest) (database.OauthClient, []string, *oauthError) {
+	client, err := app.DB.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		return client, nil, &oauthError{Error: "invalid_request", Description: "Unknown client"}
+	}
+
+	redirectURIs := strings.Fields(client.RedirectUris)
+	if req.RedirectURI == "" && len(redirectURIs) == 1 {
+		req.RedirectURI = redirectURIs[0]
+	}
+	if !slices.Contains(redirectURIs, req.RedirectURI) {
+		return client, nil, &oauthError{Error: "invalid_request", Description: "Redirect URI is not registered for the client"}
+	}
+
+	if req.ResponseType != "code" {
+		return client, nil, req.errorRedirect("unsupported_response_type", "Only the authorization code flow is supported")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return client, nil, req.errorRedirect("invalid_request", "PKCE with the S256 method is required")
+	}
+
+	scopes, err := auth.ParseScope(req.Scope)
+	if err != nil {
+		return client, nil, req.errorRedirect("invalid_scope", err.Error())
+	}
+	if len(scopes) == 0 {
+		return client, nil, req.errorRedirect("invalid_scope", "Scope is required")
+	}
+
+	return client, scopes, nil
+}
+
+// getAuthorizationHandler validates an authorization request and describes what the client asks for,
+// so that the frontend can show the consent screen.
+func (app *application) getAuthorizationHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	req := authorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	client, scopes, oauthErr := app.validateAuthorizationRequest(r.Context(), &req)
+	if oauthErr != nil {
+		respondWithJSON(w, http.StatusBadRequest, oauthErr)
+		return
+	}
+
+	type scopeResponse struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	scopesResponse := make([]scopeResponse, len(scopes))
+	for i, scope := range scopes {
+		scopesResponse[i] = scopeResponse{Name: scope, Description: auth.OAuthScopes[scope]}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		ClientID    string          `json:"client_id"`
+		ClientName  string          `json:"client_name"`
+		Scopes      []scopeResponse `json:"scopes"`
+		RedirectURI string          `json:"redirect_uri"`
+		State       string          `json:"state,omitempty"`
+	}{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Scopes:      scopesResponse,
+		RedirectURI: req.RedirectURI,
+		State:       req.State,
+	})
+}
+
+// authorizeHandler records the decision of the user on the consent screen and responds with
+// the redirect URI carrying either an authorization code or an access_denied error.
+func (app *application) authorizeHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		authorizationRequest
+		Approve bool `json:"approve"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	req := body.authorizationRequest
+	client, scopes, oauthErr := app.validateAuthorizationRequest(r.Context(), &req)
+	if oauthErr != nil {
+		respondWithJSON(w, http.StatusBadRequest, oauthErr)
+		return
+	}
+
+	if !body.Approve {
+		log.Printf("User %q denied access to OAuth client %q", user.ID, client.ID)
+		respondWithJSON(w, http.StatusOK, req.errorRedirect("access_denied", "The user denied the request"))
+		return
+	}
+
+	code, err := auth.GenerateOAuthCode()
+	if err != nil {
+		log.Printf("Failed to generate authorization code: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.RedirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(auth.OAuthCodeTTL).UTC().Format(timestampFormat),
+	})
+	if err != nil {
+		log.Printf("Failed to store authorization code: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("code", code)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	log.Printf("User %q granted %q to OAuth client %q", user.ID, strings.Join(scopes, " "), client.ID)
+	respondWithJSON(w, http.StatusOK, &struct {
+		RedirectURI string `json:"redirect_uri"`
+	}{
+		RedirectURI: withQuery(req.RedirectURI, query),
+	})
+}
+
+// authenticateOAuthClient identifies the client by HTTP Basic authentication or by
+// client_id and client_secret form parameters. Confidential clients must present their secret.
+func (app *application) authenticateOAuthClient(r *http.Request) (database.OauthClient, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	client, err := app.DB.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return client, false
+	}
+	if client.SecretHash == "" {
+		return client, secret == ""
+	}
+	return client, subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash)) == 1
+}
+
+// tokenHandler is the token endpoint (RFC 6749, section 3.2) supporting the authorization_code
+// grant with PKCE and the refresh_token grant. Refresh tokens are rotated on every use.
+func (app *application) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	client, ok := app.authenticateOAuthClient(r)
+	if !ok {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	var userID, grantID, scope string
+	// Refresh tokens are rotated by revoking the token and the rest of the grant before issuing new ones
+	var refreshTokenID string
+
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		// Only the client the code was issued to can use it up
+		code, err := app.DB.ConsumeOAuthCode(r.Context(), database.ConsumeOAuthCodeParams{
+			CodeHash: auth.HashToken(r.PostFormValue("code")),
+			ClientID: client.ID,
+		})
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code is invalid or was already used")
+			return
+		}
+		if expired(code.ExpiresAt) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code is invalid or expired")
+			return
+		}
+		if r.PostFormValue("redirect_uri") != code.RedirectUri {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Redirect URI doesn't match the authorization request")
+			return
+		}
+		if !auth.VerifyCodeChallenge(r.PostFormValue("code_verifier"), code.CodeChallenge) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code verifier doesn't match the code challenge")
+			return
+		}
+		userID, grantID, scope = code.UserID, utils.GenerateUniqueId(), code.Scope
+
+	case "refresh_token":
+		token, err := app.DB.GetOAuthToken(r.Context(), auth.HashToken(r.PostFormValue("refresh_token")))
+		if err != nil || token.Kind != "refresh" || token.ClientID != client.ID {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token is invalid")
+			return
+		}
+		if expired(token.ExpiresAt) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token is expired")
+			return
+		}
+
+		scope = token.Scope
+		if requested := r.PostFormValue("scope"); requested != "" {
+			scopes, err := auth.ParseScope(requested)
+			if err != nil {
+				respondWithOAuthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+				return
+			}
+			for _, s := range scopes {
+				if !auth.HasScope(token.Scope, s) {
+					respondWithOAuthError(w, http.StatusBadRequest, "invalid_scope", "Scope exceeds the one originally granted")
+					return
+				}
+			}
+			scope = strings.Join(scopes, " ")
+		}
+
+		userID, grantID, refreshTokenID = token.UserID, token.GrantID, token.ID
+
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Supported grant types are authorization_code and refresh_token")
+		return
+	}
+
+	accessToken, refreshToken, err := app.issueOAuthTokens(r.Context(), client.ID, userID, grantID, scope, refreshTokenID)
+	if errors.Is(err, errRefreshTokenReused) {
+		log.Printf("Reuse of a rotated refresh token of grant %q, revoked the grant", grantID)
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token is invalid")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to issue OAuth tokens: %s", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.OAuthAccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	})
+}
+
+// errRefreshTokenReused is returned when a refresh token is used after it was rotated
+var errRefreshTokenReused = errors.New("refresh token was already used")
+
+// issueOAuthTokens stores a new pair of access and refresh tokens of the grant and returns them.
+// If refreshTokenID is set, the refresh token and the rest of the grant are revoked in the same
+// transaction, so that a failure leaves the grant as it was. Only one request can revoke the
+// refresh token; a revoked token being used again means it has leaked, so the whole grant is
+// revoked and errRefreshTokenReused is returned.
+func (app *application) issueOAuthTokens(ctx context.Context, clientID, userID, grantID, scope, refreshTokenID string) (string, string, error) {
+	accessToken, err := auth.GenerateOAuthAccessToken()
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := auth.GenerateOAuthRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC()
+	tokens := []database.CreateOAuthTokenParams{
+		{
+			TokenHash: auth.HashToken(accessToken),
+			Kind:      "access",
+			ExpiresAt: now.Add(auth.OAuthAccessTokenTTL).Format(timestampFormat),
+		},
+		{
+			TokenHash: auth.HashToken(refreshToken),
+			Kind:      "refresh",
+			ExpiresAt: now.Add(auth.OAuthRefreshTokenTTL).Format(timestampFormat),
+		},
+	}
+
+	tx, err := app.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+	qtx := app.DB.WithTx(tx)
+
+	if refreshTokenID != "" {
+		revoked, err := qtx.RevokeOAuthToken(ctx, refreshTokenID)
+		if err != nil {
+			return "", "", err
+		}
+		if err := qtx.RevokeOAuthGrant(ctx, grantID); err != nil {
+			return "", "", err
+		}
+		if revoked == 0 {
+			if err := tx.Commit(); err != nil {
+				return "", "", err
+			}
+			return "", "", errRefreshTokenReused
+		}
+	}
+	for _, token := range tokens {
+		token.ID = utils.GenerateUniqueId()
+		token.GrantID = grantID
+		token.ClientID = clientID
+		token.UserID = userID
+		token.Scope = scope
+		if err := qtx.CreateOAuthToken(ctx, token); err != nil {
+			return "", "", err
+		}
+	}
+	return accessToken, refreshToken, tx.Commit()
+}
+
+// activeOAuthToken returns the token if it exists, wasn't revoked and hasn't expired.
+func (app *application) activeOAuthToken(ctx context.Context, token string) (database.OauthToken, bool) {
+	stored, err := app.DB.GetOAuthToken(ctx, auth.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to fetch OAuth token: %s", err)
+		}
+		return stored, false
+	}
+	return stored, !stored.RevokedAt.Valid && !expired(stored.ExpiresAt)
+}
+
+// introspectHandler is the token introspection endpoint (RFC 7662). Clients may only
+// introspect their own tokens; tokens of other clients are reported as inactive.
+func (app *application) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := app.authenticateOAuthClient(r)
+	if !ok {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	type introspection struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Username  string `json:"username,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		Iat       int64  `json:"iat,omitempty"`
+		Sub       string `json:"sub,omitempty"`
+	}
+
+	token, active := app.activeOAuthToken(r.Context(), r.PostFormValue("token"))
+	if !active || token.ClientID != client.ID {
+		respondWithJSON(w, http.StatusOK, &introspection{Active: false})
+		return
+	}
+
+	user, err := app.DB.GetUserByID(r.Context(), token.UserID)
+	if err != nil {
+		respondWithJSON(w, http.StatusOK, &introspection{Active: false})
+		return
+	}
+
+	expiresAt, _ := parseTimestamp(token.ExpiresAt)
+	issuedAt, _ := parseTimestamp(token.CreatedAt)
+	respondWithJSON(w, http.StatusOK, &introspection{
+		Active:    true,
+		Scope:     token.Scope,
+		ClientID:  token.ClientID,
+		Username:  user.Username,
+		TokenType: token.Kind + "_token",
+		Exp:       expiresAt.Unix(),
+		Iat:       issuedAt.Unix(),
+		Sub:       user.ID,
+	})
+}
+
+// revokeHandler is the token revocation endpoint (RFC 7009). Revoking a refresh token
+// revokes all tokens of the grant. Unknown tokens are not an error.
+func (app *application) revokeHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := app.authenticateOAuthClient(r)
+	if !ok {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	token, err := app.DB.GetOAuthToken(r.Context(), auth.HashToken(r.PostFormValue("token")))
+	if err == nil && token.ClientID == client.ID {
+		if token.Kind == "refresh" {
+			err = app.DB.RevokeOAuthGrant(r.Context(), token.GrantID)
+		} else {
+			_, err = app.DB.RevokeOAuthToken(r.Context(), token.ID)
+		}
+		if err != nil {
+			log.Printf("Failed to revoke OAuth token %q: %s", token.ID, err)
+			respondWithOAuthError(w, http.StatusServiceUnavailable, "server_error", "")
+			return
+		}
+		log.Printf("OAuth client %q revoked token %q", client.ID, token.ID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// userForOAuthToken returns the user who granted the access token, if the token is active and has the scope.
+func (app *application) userForOAuthToken(ctx context.Context, token, scope string) (database.User, *authError) {
+	stored, active := app.activeOAuthToken(ctx, token)
+	if !active || stored.Kind != "access" {
+		return database.User{}, &authError{status: http.StatusUnauthorized, msg: "token is not valid"}
+	}
+	if scope == "" || !auth.HasScope(stored.Scope, scope) {
+		return database.User{}, &authError{status: http.StatusForbidden, msg: "token doesn't have the required scope"}
+	}
+
+	user, err := app.DB.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return database.User{}, &authError{status: http.StatusNotFound, msg: http.StatusText(http.StatusNotFound)}
+	}
+	return user, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/oidc"
+)
+
+const testRedirectURI = "https://client.example/callback"
+
+func createTestOAuthClient(t *testing.T, app *application, owner database.User) database.OauthClient {
+	t.Helper()
+	client, err := app.DB.CreateOAuthClient(context.Background(), database.CreateOAuthClientParams{
+		ID:           randomTestString(t),
+		Name:         "Client",
+		RedirectUris: testRedirectURI,
+		UserID:       owner.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func randomTestString(t *testing.T) string {
+	t.Helper()
+	s, err := oidc.RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// createTestOAuthCode issues an authorization code to the client and returns it with its PKCE verifier.
+func createTestOAuthCode(t *testing.T, app *application, client database.OauthClient, user database.User) (string, string) {
+	t.Helper()
+	code, verifier := randomTestString(t), randomTestString(t)
+	err := app.DB.CreateOAuthCode(context.Background(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectUri:   testRedirectURI,
+		Scope:         "notes:read",
+		CodeChallenge: oidc.CodeChallenge(verifier),
+		ExpiresAt:     time.Now().UTC().Add(time.Minute).Format(timestampFormat),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, verifier
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+}
+
+func requestToken(t *testing.T, app *application, form url.Values) (int, tokenResponse) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.tokenHandler(w, r)
+
+	var body tokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("token endpoint responded with %d: %s", w.Code, w.Body)
+	}
+	return w.Code, body
+}
+
+func TestTokenHandlerKeepsCodeOfAnotherClient(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	client := createTestOAuthClient(t, app, user)
+	other := createTestOAuthClient(t, app, user)
+	code, verifier := createTestOAuthCode(t, app, client, user)
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	}
+
+	form.Set("client_id", other.ID)
+	if status, body := requestToken(t, app, form); status != http.StatusBadRequest || body.Error != "invalid_grant" {
+		t.Fatalf("another client redeemed the code: %d %+v", status, body)
+	}
+
+	form.Set("client_id", client.ID)
+	if status, body := requestToken(t, app, form); status != http.StatusOK || body.AccessToken == "" {
+		t.Fatalf("code was used up by another client: %d %+v", status, body)
+	}
+}
+
+func TestTokenHandlerRotatesRefreshTokens(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	client := createTestOAuthClient(t, app, user)
+	code, verifier := createTestOAuthCode(t, app, client, user)
+
+	_, first := requestToken(t, app, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+	refresh := url.Values{"grant_type": {"refresh_token"}, "client_id": {client.ID}, "refresh_token": {first.RefreshToken}}
+	status, second := requestToken(t, app, refresh)
+	if status != http.StatusOK || second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh responded with %d %+v", status, second)
+	}
+
+	ctx := context.Background()
+	if _, ok := app.activeOAuthToken(ctx, first.AccessToken); ok {
+		t.Error("access token is active after rotation")
+	}
+	if _, ok := app.activeOAuthToken(ctx, second.AccessToken); !ok {
+		t.Error("new access token isn't active")
+	}
+
+	// Reuse of a rotated refresh token revokes the grant
+	if status, _ := requestToken(t, app, refresh); status != http.StatusBadRequest {
+		t.Fatalf("rotated refresh token was accepted: %d", status)
+	}
+	if _, ok := app.activeOAuthToken(ctx, second.AccessToken); ok {
+		t.Error("grant wasn't revoked after a rotated refresh token was reused")
+	}
+}
+
+func TestTokenHandlerDetectsConcurrentRefreshTokenReuse(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	client := createTestOAuthClient(t, app, user)
+	code, verifier := createTestOAuthCode(t, app, client, user)
+
+	_, first := requestToken(t, app, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+	refresh := url.Values{"grant_type": {"refresh_token"}, "client_id": {client.ID}, "refresh_token": {first.RefreshToken}}
+
+	const requests = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	statuses := make([]int, requests)
+	responses := make([]tokenResponse, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			statuses[i], responses[i] = requestToken(t, app, refresh)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var issued []tokenResponse
+	for i, status := range statuses {
+		if status == http.StatusOK {
+			issued = append(issued, responses[i])
+		} else if status != http.StatusBadRequest {
+			t.Errorf("refresh responded with %d", status)
+		}
+	}
+	if len(issued) != 1 {
+		t.Fatalf("%d of %d concurrent refreshes with the same token succeeded, want 1", len(issued), requests)
+	}
+	// The other requests reused the token, which revokes the grant along with the new tokens
+	if _, ok := app.activeOAuthToken(context.Background(), issued[0].AccessToken); ok {
+		t.Error("grant wasn't revoked after concurrent reuse of a refresh token")
+	}
+}
+
+func TestIssueOAuthTokensRotatesRefreshTokenOnce(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	client := createTestOAuthClient(t, app, user)
+	_, refreshToken, err := app.issueOAuthTokens(ctx, client.ID, user.ID, "grant", "notes:read", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.DB.GetOAuthToken(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two requests that both found the refresh token active before either rotated it
+	accessToken, _, err := app.issueOAuthTokens(ctx, client.ID, user.ID, "grant", "notes:read", token.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := app.issueOAuthTokens(ctx, client.ID, user.ID, "grant", "notes:read", token.ID); !errors.Is(err, errRefreshTokenReused) {
+		t.Fatalf("second rotation of the refresh token returned %v, want %v", err, errRefreshTokenReused)
+	}
+	if _, ok := app.activeOAuthToken(ctx, accessToken); ok {
+		t.Error("grant wasn't revoked after the refresh token was reused")
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/oidc"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+	"github.com/mattn/go-sqlite3"
+)
+
+// loadOIDCProviders reads OpenID providers from the environment. OIDC_PROVIDERS lists provider
+// names separated by commas, and each provider is configured with OIDC_<NAME>_ISSUER,
+// OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL
+// and optionally OIDC_<NAME>_SCOPES separated by spaces.
+func loadOIDCProviders() map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			log.Printf("Skipping OpenID provider %q: issuer, client ID and redirect URL are required", name)
+			continue
+		}
+		providers[name] = oidc.NewProvider(config, nil)
+		log.Printf("Enabled sign in with OpenID provider %q", name)
+	}
+	return providers
+}
+
+// startOIDCLoginHandler redirects the user to the OpenID provider.
+// The login is completed by oidcCallbackHandler.
+func (app *application) startOIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	app.startOIDCFlow(w, r, "")
+}
+
+// startOIDCLinkHandler redirects the signed in user to the OpenID provider to link
+// the identity to their account. The link is completed by oidcCallbackHandler.
+func (app *application) startOIDCLinkHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	app.startOIDCFlow(w, r, user.ID)
+}
+
+// startOIDCFlow redirects to the OpenID provider from the path, either to sign in
+// or, if linkUserID is set, to link the identity to that user.
+func (app *application) startOIDCFlow(w http.ResponseWriter, r *http.Request, linkUserID string) {
+	name := r.PathValue("provider")
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		http.Error(w, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	var state auth.OIDCState
+	var err error
+	state.Provider = name
+	state.CookieMode = cookieMode(r)
+	state.LinkUserID = linkUserID
+	if state.State, err = oidc.RandomString(); err == nil {
+		if state.Nonce, err = oidc.RandomString(); err == nil {
+			state.CodeVerifier, err = oidc.RandomString()
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to generate OpenID login state: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	redirectURL, err := provider.AuthCodeURL(r.Context(), state.State, state.Nonce, oidc.CodeChallenge(state.CodeVerifier))
+	if err != nil {
+		log.Printf("Failed to start login with OpenID provider %q: %s", name, err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	if err := auth.SetOIDCStateCookie(w, state); err != nil {
+		log.Printf("Failed to store OpenID login state: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		http.Error(w, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	state, err := auth.OIDCStateFromCookie(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	if state.Provider != name || query.Get("state") != state.State {
+		log.Printf("OpenID login state mismatch for provider %q", name)
+		http.Error(w, "Login state doesn't match", http.StatusBadRequest)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		log.Printf("OpenID provider %q returned an error: %s", name, errCode)
+		http.Error(w, "Identity provider refused the login", http.StatusUnauthorized)
+		return
+	}
+	if query.Get("code") == "" {
+		http.Error(w, "Authorization code was not provided", http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := provider.Exchange(r.Context(), query.Get("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("Failed to complete login with OpenID provider %q: %s", name, err)
+		http.Error(w, "Failed to verify the login with the identity provider", http.StatusUnauthorized)
+		return
+	}
+
+	if state.LinkUserID != "" {
+		app.linkIdentity(w, r, name, idToken, state.LinkUserID)
+		return
+	}
+
+	user, err := app.userForIdentity(r.Context(), name, idToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, errIdentityWithoutEmail):
+			http.Error(w, "Identity provider didn't share an email address", http.StatusBadRequest)
+			return
+		case errors.Is(err, errIdentityEmailInUse):
+			http.Error(w, "Email is already in use, sign in to link the identity to your account", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to sign in with OpenID provider %q: %s", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// The provider only replaces the password, so the second factor is still required
+	if app.requireSecondFactor(w, r, user) {
+		return
+	}
+	app.respondWithNewSession(w, r, user.ID, user.Email, state.CookieMode)
+}
+
+var (
+	errIdentityWithoutEmail = errors.New("identity has no email")
+	errIdentityEmailInUse   = errors.New("email of the identity belongs to another user")
+)
+
+// userForIdentity returns the user linked to the external identity. Unknown identities are
+// linked to the user with the same email if both the provider and the user have verified it,
+// otherwise a new user is created. Anyone can sign up with an email they don't own, so the
+// owner of the email has to sign in to that account and link the identity themselves.
+func (app *application) userForIdentity(ctx context.Context, provider string, idToken *oidc.IDToken) (database.User, error) {
+	identity, err := app.DB.GetIdentity(ctx, database.GetIdentityParams{
+		Provider: provider,
+		Subject:  idToken.Subject,
+	})
+	if err == nil {
+		return app.DB.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	if idToken.Email == "" {
+		return database.User{}, errIdentityWithoutEmail
+	}
+
+	user, err := app.DB.GetUserByEmail(ctx, idToken.Email)
+	switch {
+	case err == nil && (!idToken.EmailVerified || user.Verified == 0):
+		return database.User{}, errIdentityEmailInUse
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = app.createUserForIdentity(ctx, idToken)
+		if err != nil {
+			return database.User{}, err
+		}
+		log.Printf("New user created with the ID %q from OpenID provider %q", user.ID, provider)
+	case err != nil:
+		return database.User{}, err
+	}
+
+	_, err = app.DB.CreateIdentity(ctx, database.CreateIdentityParams{
+		ID:       utils.GenerateUniqueId(),
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	log.Printf("Linked identity from OpenID provider %q to user %q", provider, user.ID)
+
+	return user, nil
+}
+
+var usernameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// createUserForIdentity creates a user without a password, so that they can only sign in
+// through the identity provider. A username is derived from the claims and made unique.
+func (app *application) createUserForIdentity(ctx context.Context, idToken *oidc.IDToken) (database.User, error) {
+	base := idToken.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(idToken.Email, "@")
+	}
+	base = usernameChars.ReplaceAllString(strings.ToLower(base), "")
+	if len(base) < 2 {
+		base = "user"
+	}
+	if len(base) > 14 {
+		base = base[:14]
+	}
+
+	name := strings.TrimSpace(idToken.Name)
+	if len([]rune(name)) < 2 {
+		name = base
+	}
+	if runes := []rune(name); len(runes) > 20 {
+		name = string(runes[:20])
+	}
+
+	username := base
+	for attempt := 0; attempt < 5; attempt++ {
+		user, err := app.DB.CreateUser(ctx, database.CreateUserParams{
+			ID:       utils.GenerateUniqueId(),
+			Email:    idToken.Email,
+			Name:     name,
+			Username: username,
+			Password: "",
+		})
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+			strings.Contains(sqliteErr.Error(), "users.username") {
+			username = base + "_" + utils.GenerateUniqueId()[:5]
+			continue
+		}
+		if err != nil || !idToken.EmailVerified {
+			return user, err
+		}
+
+		// The provider has proven the user owns the email
+		err = app.DB.SetUserVerified(ctx, database.SetUserVerifiedParams{Verified: 1, ID: user.ID})
+		user.Verified = 1
+		return user, err
+	}
+	return database.User{}, errors.New("failed to find a free username")
+}
+
+// linkIdentity links the external identity to the user who started the link.
+func (app *application) linkIdentity(w http.ResponseWriter, r *http.Request, provider string, idToken *oidc.IDToken, userID string) {
+	identity, err := app.DB.GetIdentity(r.Context(), database.GetIdentityParams{
+		Provider: provider,
+		Subject:  idToken.Subject,
+	})
+	if err == nil {
+		if identity.UserID != userID {
+			http.Error(w, "Identity is linked to another user", http.StatusConflict)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, &identity)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to fetch identity from OpenID provider %q: %s", provider, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	identity, err = app.DB.CreateIdentity(r.Context(), database.CreateIdentityParams{
+		ID:       utils.GenerateUniqueId(),
+		UserID:   userID,
+		Provider: provider,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+	})
+	if err != nil {
+		log.Printf("Failed to link identity from OpenID provider %q to user %q: %s", provider, userID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Linked identity from OpenID provider %q to user %q", provider, userID)
+	respondWithJSON(w, http.StatusCreated, &identity)
+}
+
+func (app *application) getIdentitiesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	identities, err := app.DB.FetchUserIdentities(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch identities of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []database.Identity{}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total      int                 `json:"total"`
+		Identities []database.Identity `json:"identities"`
+	}{
+		Total:      len(identities),
+		Identities: identities,
+	})
+}
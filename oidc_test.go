@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/oidc"
+	"github.com/chtozamm/annynotes-go/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newOIDCTestApp(t *testing.T) (*application, *oidctest.Server) {
+	t.Helper()
+	app := newTestApp(t)
+	srv := oidctest.NewServer(t, "annynotes")
+	app.oidcProviders = map[string]*oidc.Provider{
+		"test": oidc.NewProvider(oidc.Config{
+			Name:        "test",
+			Issuer:      srv.Issuer(),
+			ClientID:    srv.ClientID,
+			RedirectURL: "https://notes.example/users/auth/oidc/test/callback",
+		}, srv.Client()),
+	}
+	return app, srv
+}
+
+// oidcLogin signs in through the provider as the user with the claims and returns the callback response.
+func oidcLogin(t *testing.T, app *application, srv *oidctest.Server, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+	return oidcFlow(t, app, srv, claims, app.startOIDCLoginHandler)
+}
+
+// oidcLink links the identity with the claims to the signed in user and returns the callback response.
+func oidcLink(t *testing.T, app *application, srv *oidctest.Server, claims jwt.MapClaims, user database.User) *httptest.ResponseRecorder {
+	t.Helper()
+	return oidcFlow(t, app, srv, claims, func(w http.ResponseWriter, r *http.Request) {
+		app.startOIDCLinkHandler(w, r, user)
+	})
+}
+
+// oidcFlow goes through the provider from the start handler to the callback.
+func oidcFlow(t *testing.T, app *application, srv *oidctest.Server, claims jwt.MapClaims, startHandler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	srv.SetClaims(claims)
+
+	start := httptest.NewRequest(http.MethodGet, "/users/auth/oidc/test", nil)
+	start.SetPathValue("provider", "test")
+	w := httptest.NewRecorder()
+	startHandler(w, start)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login start responded with %d: %s", w.Code, w.Body)
+	}
+
+	// The provider signs the user in right away and redirects back with a code
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("provider didn't redirect back: %s", resp.Status)
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/users/auth/oidc/test/callback?"+location.RawQuery, nil)
+	callback.SetPathValue("provider", "test")
+	for _, cookie := range w.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	app.oidcCallbackHandler(w, callback)
+	return w
+}
+
+// loggedInUser returns the ID of the user the callback response signed in.
+func loggedInUser(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback responded with %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Token == "" {
+		t.Fatalf("callback didn't respond with a token: %s", w.Body)
+	}
+	claims, err := auth.ValidateJWT(http.Header{"Authorization": {"Bearer " + body.Token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.UserID
+}
+
+func userIdentities(t *testing.T, app *application, userID string) []database.Identity {
+	t.Helper()
+	identities, err := app.DB.FetchUserIdentities(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identities
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	claims := jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true, "preferred_username": "Ann.Lee"}
+
+	userID := loggedInUser(t, oidcLogin(t, app, srv, claims))
+	user, err := app.DB.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "ann@example.com" || user.Username != "annlee" || user.Password != "" {
+		t.Errorf("unexpected user: %+v", user)
+	}
+	if identities := userIdentities(t, app, userID); len(identities) != 1 || identities[0].Subject != "subject-1" {
+		t.Errorf("unexpected identities: %+v", identities)
+	}
+
+	// The identity signs in the same user from now on
+	if again := loggedInUser(t, oidcLogin(t, app, srv, claims)); again != userID {
+		t.Errorf("second login signed in user %q, want %q", again, userID)
+	}
+}
+
+func TestOIDCLoginCreatesUserWithFreeUsername(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	createTestUser(t, app, "ann", "someone@example.com")
+
+	userID := loggedInUser(t, oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com"}))
+	user, err := app.DB.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username == "ann" {
+		t.Error("new user got a username that is taken")
+	}
+}
+
+// verifyTestUser marks the email of the user as verified.
+func verifyTestUser(t *testing.T, app *application, user database.User) database.User {
+	t.Helper()
+	err := app.DB.SetUserVerified(context.Background(), database.SetUserVerifiedParams{Verified: 1, ID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Verified = 1
+	return user
+}
+
+func TestOIDCLoginLinksUserWithVerifiedEmail(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	existing := verifyTestUser(t, app, createTestUser(t, app, "ann", "ann@example.com"))
+
+	userID := loggedInUser(t, oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true}))
+	if userID != existing.ID {
+		t.Fatalf("signed in user %q, want %q", userID, existing.ID)
+	}
+	if identities := userIdentities(t, app, existing.ID); len(identities) != 1 {
+		t.Errorf("identity wasn't linked: %+v", identities)
+	}
+}
+
+func TestOIDCLoginRefusesUserWhoDidntVerifyEmail(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	// Anyone could have signed up with the email before its owner
+	squatter := createTestUser(t, app, "ann", "ann@example.com")
+
+	w := oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("callback responded with %d, want %d", w.Code, http.StatusConflict)
+	}
+	if identities := userIdentities(t, app, squatter.ID); len(identities) != 0 {
+		t.Errorf("identity was linked to a user who didn't verify the email: %+v", identities)
+	}
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	claims := jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true}
+
+	if w := oidcLink(t, app, srv, claims, user); w.Code != http.StatusCreated {
+		t.Fatalf("link responded with %d: %s", w.Code, w.Body)
+	}
+	if identities := userIdentities(t, app, user.ID); len(identities) != 1 {
+		t.Fatalf("identity wasn't linked: %+v", identities)
+	}
+	if userID := loggedInUser(t, oidcLogin(t, app, srv, claims)); userID != user.ID {
+		t.Errorf("linked identity signed in user %q, want %q", userID, user.ID)
+	}
+
+	other := createTestUser(t, app, "bob", "bob@example.com")
+	if w := oidcLink(t, app, srv, claims, other); w.Code != http.StatusConflict {
+		t.Errorf("linking an identity of another user responded with %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestOIDCLoginVerifiesCreatedUser(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	userID := loggedInUser(t, oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true}))
+
+	user, err := app.DB.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Verified != 1 {
+		t.Error("user created from a verified email isn't verified")
+	}
+
+	// Another provider account with the same verified email signs in the same user
+	if again := loggedInUser(t, oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-2", "email": "ann@example.com", "email_verified": true})); again != userID {
+		t.Errorf("signed in user %q, want %q", again, userID)
+	}
+}
+
+func TestOIDCLoginRefusesUnverifiedEmailInUse(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	existing := createTestUser(t, app, "ann", "ann@example.com")
+
+	w := oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": false})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("callback responded with %d, want %d", w.Code, http.StatusConflict)
+	}
+	if identities := userIdentities(t, app, existing.ID); len(identities) != 0 {
+		t.Errorf("identity was linked: %+v", identities)
+	}
+}
+
+func TestOIDCLoginRequiresEmail(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	w := oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("callback responded with %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCLoginRequiresSecondFactor(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	user := verifyTestUser(t, app, createTestUser(t, app, "ann", "ann@example.com"))
+	ctx := context.Background()
+	if _, err := app.DB.SetupTwoFactor(ctx, database.SetupTwoFactorParams{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.DB.EnableTwoFactor(ctx, database.EnableTwoFactorParams{UserID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := oidcLogin(t, app, srv, jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com", "email_verified": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback responded with %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Token             string `json:"token"`
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Token != "" || !body.TwoFactorRequired || body.ChallengeToken == "" {
+		t.Errorf("callback didn't require the second factor: %s", w.Body)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name != auth.OIDCStateCookieName && cookie.MaxAge >= 0 {
+			t.Errorf("callback set cookie %q before the second factor", cookie.Name)
+		}
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	app, srv := newOIDCTestApp(t)
+	srv.SetClaims(jwt.MapClaims{"sub": "subject-1", "email": "ann@example.com"})
+
+	start := httptest.NewRequest(http.MethodGet, "/users/auth/oidc/test", nil)
+	start.SetPathValue("provider", "test")
+	w := httptest.NewRecorder()
+	app.startOIDCLoginHandler(w, start)
+
+	callback := httptest.NewRequest(http.MethodGet, "/users/auth/oidc/test/callback?code=code&state=forged", nil)
+	callback.SetPathValue("provider", "test")
+	for _, cookie := range w.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	app.oidcCallbackHandler(w, callback)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("callback responded with %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	app, _ := newOIDCTestApp(t)
+	callback := httptest.NewRequest(http.MethodGet, "/users/auth/oidc/test/callback?code=code&state=state", nil)
+	callback.SetPathValue("provider", "test")
+	w := httptest.NewRecorder()
+	app.oidcCallbackHandler(w, callback)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("callback responded with %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+	"github.com/chtozamm/annynotes-go/internal/webauthn"
+)
+
+// How long a user has to complete a WebAuthn ceremony
+const webauthnTimeout = 5 * time.Minute
+
+// loadRelyingParty reads the WebAuthn relying party from WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME
+// and WEBAUTHN_ORIGINS, a comma-separated list of frontend origins.
+func loadRelyingParty() *webauthn.RelyingParty {
+	rp := &webauthn.RelyingParty{
+		ID:   os.Getenv("WEBAUTHN_RP_ID"),
+		Name: os.Getenv("WEBAUTHN_RP_NAME"),
+	}
+	if rp.ID == "" {
+		rp.ID = "localhost"
+	}
+	if rp.Name == "" {
+		rp.Name = "Annynotes"
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			rp.Origins = append(rp.Origins, origin)
+		}
+	}
+	if len(rp.Origins) == 0 {
+		rp.Origins = []string{"http://localhost:3000"}
+	}
+	return rp
+}
+
+// credentialDescriptor identifies a credential in WebAuthn options.
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// credentialResponse is the JSON serialization of a PublicKeyCredential
+// as returned by its toJSON() method. Binary fields are base64url-encoded.
+type credentialResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// decodeCredential decodes the credential and its challenge. Unknown fields are allowed,
+// as browsers add fields to the serialization over time.
+func decodeCredential(raw json.RawMessage) (*credentialResponse, []byte, string, error) {
+	var credential credentialResponse
+	if err := json.Unmarshal(raw, &credential); err != nil || credential.Type != "public-key" {
+		return nil, nil, "", errors.New("Malformed credential")
+	}
+
+	clientDataJSON, err := base64.RawURLEncoding.DecodeString(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, "", errors.New("Malformed client data")
+	}
+	var clientData struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, nil, "", errors.New("Malformed client data")
+	}
+
+	return &credential, clientDataJSON, clientData.Challenge, nil
+}
+
+// newWebAuthnChallenge stores a challenge for a ceremony of the user.
+// The user is empty for authentication, as passkeys are discoverable.
+func (app *application) newWebAuthnChallenge(ctx context.Context, ceremony, userID string) (string, error) {
+	now := time.Now().UTC()
+	if err := app.DB.DeleteExpiredWebAuthnChallenges(ctx, now.Format(timestampFormat)); err != nil {
+		log.Printf("Failed to delete expired WebAuthn challenges: %s", err)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	err = app.DB.CreateWebAuthnChallenge(ctx, database.CreateWebAuthnChallengeParams{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: now.Add(webauthnTimeout).Format(timestampFormat),
+	})
+	return challenge, err
+}
+
+// consumeWebAuthnChallenge returns true if the challenge was issued for the ceremony
+// of the user and hasn't expired. Each challenge can be consumed once.
+func (app *application) consumeWebAuthnChallenge(ctx context.Context, challenge, ceremony, userID string) bool {
+	stored, err := app.DB.ConsumeWebAuthnChallenge(ctx, challenge)
+	if err != nil {
+		return false
+	}
+	return stored.Ceremony == ceremony && stored.UserID == userID && !expired(stored.ExpiresAt)
+}
+
+func (app *application) beginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	challenge, err := app.newWebAuthnChallenge(r.Context(), "registration", user.ID)
+	if err != nil {
+		log.Printf("Failed to create WebAuthn challenge: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	passkeys, err := app.DB.FetchUserPasskeys(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch passkeys of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// Don't register the same authenticator twice
+	exclude := make([]credentialDescriptor, len(passkeys))
+	for i, passkey := range passkeys {
+		exclude[i] = credentialDescriptor{Type: "public-key", ID: passkey.CredentialID}
+	}
+
+	type pubKeyCredParam struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	}
+	params := make([]pubKeyCredParam, len(webauthn.SupportedAlgorithms))
+	for i, alg := range webauthn.SupportedAlgorithms {
+		params[i] = pubKeyCredParam{Type: "public-key", Alg: alg}
+	}
+
+	// PublicKeyCredentialCreationOptionsJSON, to be passed to PublicKeyCredential.parseCreationOptionsFromJSON()
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"challenge": challenge,
+		"rp": map[string]string{
+			"id":   app.relyingParty.ID,
+			"name": app.relyingParty.Name,
+		},
+		"user": map[string]string{
+			"id":          base64.RawURLEncoding.EncodeToString([]byte(user.ID)),
+			"name":        user.Email,
+			"displayName": user.Name,
+		},
+		"pubKeyCredParams":   params,
+		"excludeCredentials": exclude,
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "required",
+			"userVerification": "required",
+		},
+		"attestation": "none",
+		"timeout":     webauthnTimeout.Milliseconds(),
+	})
+}
+
+func (app *application) createPasskeyHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name       string          `json:"name"`
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if body.Name == "" {
+		body.Name = "Passkey"
+	}
+	if len(body.Name) > 50 {
+		http.Error(w, "Name must be at most 50 characters long", http.StatusBadRequest)
+		return
+	}
+
+	credential, clientDataJSON, challenge, err := decodeCredential(body.Credential)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	attestationObject, err := base64.RawURLEncoding.DecodeString(credential.Response.AttestationObject)
+	if err != nil {
+		http.Error(w, "Malformed attestation object", http.StatusBadRequest)
+		return
+	}
+
+	if !app.consumeWebAuthnChallenge(r.Context(), challenge, "registration", user.ID) {
+		http.Error(w, "Challenge is invalid or expired", http.StatusBadRequest)
+		return
+	}
+
+	created, err := app.relyingParty.VerifyRegistration(challenge, clientDataJSON, attestationObject, true)
+	if err != nil {
+		log.Printf("Failed to verify passkey registration of user %q: %s", user.ID, err)
+		http.Error(w, "Passkey registration failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := app.DB.CreatePasskey(r.Context(), database.CreatePasskeyParams{
+		ID:           utils.GenerateUniqueId(),
+		UserID:       user.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(created.ID),
+		PublicKey:    created.PublicKey,
+		SignCount:    int64(created.SignCount),
+		Name:         body.Name,
+	})
+	if err != nil {
+		log.Printf("Failed to store passkey of user %q: %s", user.ID, err)
+		http.Error(w, "Passkey is already registered", http.StatusConflict)
+		return
+	}
+
+	log.Printf("User %q registered passkey %q", user.ID, passkey.ID)
+	respondWithJSON(w, http.StatusCreated, newPasskeyResponse(passkey))
+}
+
+type passkeyResponse struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+}
+
+func newPasskeyResponse(passkey database.Passkey) passkeyResponse {
+	return passkeyResponse{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt.String,
+	}
+}
+
+func (app *application) getPasskeysHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	passkeys, err := app.DB.FetchUserPasskeys(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch passkeys of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]passkeyResponse, len(passkeys))
+	for i, passkey := range passkeys {
+		result[i] = newPasskeyResponse(passkey)
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total    int               `json:"total"`
+		Passkeys []passkeyResponse `json:"passkeys"`
+	}{
+		Total:    len(result),
+		Passkeys: result,
+	})
+}
+
+func (app *application) deletePasskeyHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	deleted, err := app.DB.DeletePasskey(r.Context(), database.DeletePasskeyParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to delete passkey %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Passkey does not exist", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("User %q deleted passkey %q", user.ID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) beginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	challenge, err := app.newWebAuthnChallenge(r.Context(), "authentication", "")
+	if err != nil {
+		log.Printf("Failed to create WebAuthn challenge: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// PublicKeyCredentialRequestOptionsJSON, to be passed to PublicKeyCredential.parseRequestOptionsFromJSON().
+	// Credentials are not listed, so that the authenticator offers any passkey it has for us.
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"challenge":        challenge,
+		"rpId":             app.relyingParty.ID,
+		"allowCredentials": []credentialDescriptor{},
+		"userVerification": "required",
+		"timeout":          webauthnTimeout.Milliseconds(),
+	})
+}
+
+// finishPasskeyLoginHandler verifies the assertion of a passkey and responds
+// with the same token as authenticateUserHandler.
+func (app *application) finishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	credential, clientDataJSON, challenge, err := decodeCredential(body.Credential)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	authenticatorData, err := base64.RawURLEncoding.DecodeString(credential.Response.AuthenticatorData)
+	if err != nil {
+		http.Error(w, "Malformed authenticator data", http.StatusBadRequest)
+		return
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(credential.Response.Signature)
+	if err != nil {
+		http.Error(w, "Malformed signature", http.StatusBadRequest)
+		return
+	}
+
+	if !app.consumeWebAuthnChallenge(r.Context(), challenge, "authentication", "") {
+		http.Error(w, "Challenge is invalid or expired", http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := app.DB.GetPasskeyByCredentialID(r.Context(), credential.ID)
+	if err != nil {
+		log.Printf("Attempt to login with an unknown passkey from %s", clientIP(r))
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if credential.Response.UserHandle != "" &&
+		credential.Response.UserHandle != base64.RawURLEncoding.EncodeToString([]byte(passkey.UserID)) {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	signCount, err := app.relyingParty.VerifyAssertion(challenge, webauthn.Credential{
+		PublicKey: passkey.PublicKey,
+		SignCount: uint32(passkey.SignCount),
+	}, clientDataJSON, authenticatorData, signature, true)
+	if err != nil {
+		log.Printf("Failed to verify passkey %q: %s", passkey.ID, err)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	err = app.DB.UpdatePasskeySignCount(r.Context(), database.UpdatePasskeySignCountParams{
+		SignCount: int64(signCount),
+		ID:        passkey.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to update passkey %q: %s", passkey.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := app.DB.GetUserByID(r.Context(), passkey.UserID)
+	if err != nil {
+		log.Printf("Failed to fetch owner of passkey %q: %s", passkey.ID, err)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("User %q logged in with passkey %q", user.ID, passkey.ID)
+	app.respondWithSession(w, r, user.ID, user.Email)
+}
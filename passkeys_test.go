@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/webauthn"
+	"github.com/chtozamm/annynotes-go/internal/webauthn/webauthntest"
+)
+
+const testWebAuthnOrigin = "https://notes.example"
+
+func newPasskeyTestApp(t *testing.T) (*application, *webauthntest.Authenticator) {
+	t.Helper()
+	app := newTestApp(t)
+	app.relyingParty = &webauthn.RelyingParty{ID: "notes.example", Name: "Annynotes", Origins: []string{testWebAuthnOrigin}}
+	a, err := webauthntest.New(app.relyingParty.ID, testWebAuthnOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, a
+}
+
+// beginCeremony calls the handler starting a ceremony and returns the challenge.
+func beginCeremony(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	var options struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &options); err != nil || options.Challenge == "" {
+		t.Fatalf("ceremony didn't start: %d %s", w.Code, w.Body)
+	}
+	return options.Challenge
+}
+
+func jsonRequest(t *testing.T, body interface{}) *http.Request {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func registerPasskey(t *testing.T, app *application, a *webauthntest.Authenticator, user database.User) *httptest.ResponseRecorder {
+	t.Helper()
+	challenge := beginCeremony(t, func(w http.ResponseWriter, r *http.Request) {
+		app.beginPasskeyRegistrationHandler(w, r, user)
+	})
+	clientData, attestation := a.Register(challenge)
+	w := httptest.NewRecorder()
+	app.createPasskeyHandler(w, jsonRequest(t, map[string]interface{}{
+		"name": "Laptop",
+		"credential": webauthntest.CredentialJSON(a.CredentialID, map[string][]byte{
+			"clientDataJSON":    clientData,
+			"attestationObject": attestation,
+		}),
+	}), user)
+	return w
+}
+
+// passkeyAssertion returns a login request body signed by the authenticator.
+func passkeyAssertion(a *webauthntest.Authenticator, challenge string, user database.User) map[string]interface{} {
+	clientData, authData, signature := a.Login(challenge)
+	return map[string]interface{}{
+		"credential": webauthntest.CredentialJSON(a.CredentialID, map[string][]byte{
+			"clientDataJSON":    clientData,
+			"authenticatorData": authData,
+			"signature":         signature,
+			"userHandle":        []byte(user.ID),
+		}),
+	}
+}
+
+func finishPasskeyLogin(t *testing.T, app *application, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	app.finishPasskeyLoginHandler(w, jsonRequest(t, body))
+	return w
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+
+	if w := registerPasskey(t, app, a, user); w.Code != http.StatusCreated {
+		t.Fatalf("registration responded with %d: %s", w.Code, w.Body)
+	}
+
+	challenge := beginCeremony(t, app.beginPasskeyLoginHandler)
+	w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user))
+	if w.Code != http.StatusOK {
+		t.Fatalf("login responded with %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Token == "" {
+		t.Fatalf("login didn't respond with a token: %s", w.Body)
+	}
+
+	passkey, err := app.DB.GetPasskeyByCredentialID(context.Background(), base64.RawURLEncoding.EncodeToString(a.CredentialID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passkey.SignCount != int64(a.SignCount) {
+		t.Errorf("stored sign count = %d, want %d", passkey.SignCount, a.SignCount)
+	}
+}
+
+func TestPasskeyRegistrationRejectsDuplicate(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+
+	registerPasskey(t, app, a, user)
+	if w := registerPasskey(t, app, a, user); w.Code != http.StatusConflict {
+		t.Fatalf("second registration responded with %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestPasskeyRegistrationRejectsChallengeOfAnotherUser(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	other := createTestUser(t, app, "bob", "bob@example.com")
+
+	challenge := beginCeremony(t, func(w http.ResponseWriter, r *http.Request) {
+		app.beginPasskeyRegistrationHandler(w, r, other)
+	})
+	clientData, attestation := a.Register(challenge)
+	w := httptest.NewRecorder()
+	app.createPasskeyHandler(w, jsonRequest(t, map[string]interface{}{
+		"credential": webauthntest.CredentialJSON(a.CredentialID, map[string][]byte{
+			"clientDataJSON":    clientData,
+			"attestationObject": attestation,
+		}),
+	}), user)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("registration responded with %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPasskeyLoginRejectsReusedChallenge(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	registerPasskey(t, app, a, user)
+
+	challenge := beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusOK {
+		t.Fatalf("login responded with %d: %s", w.Code, w.Body)
+	}
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusBadRequest {
+		t.Fatalf("login with a used challenge responded with %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPasskeyLoginRejectsCounterRegression(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	registerPasskey(t, app, a, user)
+
+	a.SignCount = 10
+	challenge := beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusOK {
+		t.Fatalf("login responded with %d: %s", w.Code, w.Body)
+	}
+
+	// A clone of the authenticator made before the last login
+	a.SignCount = 5
+	challenge = beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login with a stale counter responded with %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPasskeyLoginRejectsWrongOriginAndRelyingParty(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	registerPasskey(t, app, a, user)
+
+	a.Origin = "https://evil.example"
+	challenge := beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusUnauthorized {
+		t.Errorf("login from another origin responded with %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	a.Origin, a.RPID = testWebAuthnOrigin, "evil.example"
+	challenge = beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, user)); w.Code != http.StatusUnauthorized {
+		t.Errorf("login for another relying party responded with %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPasskeyLoginRejectsWrongUserHandle(t *testing.T) {
+	app, a := newPasskeyTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	other := createTestUser(t, app, "bob", "bob@example.com")
+	registerPasskey(t, app, a, user)
+
+	challenge := beginCeremony(t, app.beginPasskeyLoginHandler)
+	if w := finishPasskeyLogin(t, app, passkeyAssertion(a, challenge, other)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login with another user handle responded with %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPasskeyLoginRejectsMalformedCredential(t *testing.T) {
+	app, _ := newPasskeyTestApp(t)
+	for _, credential := range []string{`{}`, `{"type":"public-key","response":{"clientDataJSON":"!"}}`, `"credential"`} {
+		w := finishPasskeyLogin(t, app, map[string]interface{}{"credential": json.RawMessage(credential)})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("login with credential %s responded with %d, want %d", credential, w.Code, http.StatusBadRequest)
+		}
+	}
+}
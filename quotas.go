@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// quota limits what a user can store. Zero means no limit.
+type quota struct {
+	Notes       int64
+	Bytes       int64
+	NotesPerDay int64
+}
+
+// defaultQuotas apply unless QUOTA_<ROLE>_NOTES, QUOTA_<ROLE>_BYTES or
+// QUOTA_<ROLE>_NOTES_PER_DAY are set.
+var defaultQuotas = map[string]quota{
+	userRoleUser:      {Notes: 1000, Bytes: 1 << 20, NotesPerDay: 100},
+	userRoleModerator: {Notes: 10000, Bytes: 10 << 20, NotesPerDay: 1000},
+}
+
+func loadQuotas() map[string]quota {
+	quotas := make(map[string]quota)
+	for role, q := range defaultQuotas {
+		prefix := "QUOTA_" + strings.ToUpper(role) + "_"
+		quotas[role] = quota{
+			Notes:       int64(utils.EnvInt(prefix+"NOTES", int(q.Notes))),
+			Bytes:       int64(utils.EnvInt(prefix+"BYTES", int(q.Bytes))),
+			NotesPerDay: int64(utils.EnvInt(prefix+"NOTES_PER_DAY", int(q.NotesPerDay))),
+		}
+	}
+	return quotas
+}
+
+// userUsage returns what the user stores and how many notes they wrote in the last 24 hours,
+// including deleted ones.
+func userUsage(ctx context.Context, db *database.Queries, user database.User) (database.GetUserUsageRow, error) {
+	return db.GetUserUsage(ctx, database.GetUserUsageParams{
+		Since:  time.Now().UTC().Add(-24 * time.Hour).Format(timestampFormat),
+		UserID: user.ID,
+	})
+}
+
+// checkQuota responds with an error and returns false if the user can't store a message of
+// the given size. A new note also counts against the number of notes.
+// Guests are held back by rate limits and proof of work instead.
+func (app *application) checkQuota(w http.ResponseWriter, r *http.Request, user database.User, newNote bool, addedBytes int64) bool {
+	if user.ID == "" {
+		return true
+	}
+	usage, err := userUsage(r.Context(), app.DB, user)
+	if err != nil {
+		log.Printf("Failed to fetch usage of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+	return app.checkUsage(w, r, user, usage, newNote, addedBytes)
+}
+
+// createNoteWithinQuota creates the note if it fits the quota of the user, and otherwise responds
+// with an error and returns false. The note is created before the check in a transaction, which
+// makes concurrent requests wait for each other, so that they can't all pass the check.
+func (app *application) createNoteWithinQuota(w http.ResponseWriter, r *http.Request, user database.User, params database.CreateNoteParams) (database.Note, bool) {
+	tx, err := app.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Failed to begin a transaction: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return database.Note{}, false
+	}
+	defer tx.Rollback()
+	qtx := app.DB.WithTx(tx)
+
+	note, err := qtx.CreateNote(r.Context(), params)
+	if err != nil {
+		log.Printf("Failed to create a new note: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return database.Note{}, false
+	}
+
+	if user.ID != "" {
+		usage, err := userUsage(r.Context(), qtx, user)
+		if err != nil {
+			log.Printf("Failed to fetch usage of user %q: %s", user.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return database.Note{}, false
+		}
+		// Check the usage as it was before the note
+		size := int64(len(note.Message))
+		usage.Notes--
+		usage.NotesSince--
+		usage.Bytes -= size
+		if !app.checkUsage(w, r, user, usage, true, size) {
+			return database.Note{}, false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to create a new note: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return database.Note{}, false
+	}
+	return note, true
+}
+
+// checkUsage responds with an error and returns false if the usage of the user leaves no room
+// for the change.
+func (app *application) checkUsage(w http.ResponseWriter, r *http.Request, user database.User, usage database.GetUserUsageRow, newNote bool, addedBytes int64) bool {
+	q := app.quotas[user.Role]
+	if newNote && q.Notes > 0 && usage.Notes >= q.Notes {
+		http.Error(w, "Note limit reached: you can have at most "+strconv.FormatInt(q.Notes, 10)+" notes", http.StatusForbidden)
+		return false
+	}
+	if q.Bytes > 0 && addedBytes > 0 && usage.Bytes+addedBytes > q.Bytes {
+		http.Error(w, "Storage limit reached: your notes can take at most "+strconv.FormatInt(q.Bytes, 10)+" bytes", http.StatusForbidden)
+		return false
+	}
+	if newNote && q.NotesPerDay > 0 && usage.NotesSince >= q.NotesPerDay {
+		// The oldest note of the last 24 hours is the next one to leave the window
+		since := time.Now().UTC().Add(-24 * time.Hour)
+		oldest, err := app.DB.GetOldestNoteSince(r.Context(), database.GetOldestNoteSinceParams{
+			UserID:    user.ID,
+			CreatedAt: since.Format(timestampFormat),
+		})
+		if err == nil {
+			if t, err := time.Parse(timestampFormat, oldest); err == nil {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(t.Sub(since))))
+			}
+		}
+		http.Error(w, "Daily limit reached: you can write at most "+strconv.FormatInt(q.NotesPerDay, 10)+" notes a day", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+type usageLimit struct {
+	Used int64 `json:"used"`
+	// Limit is empty if there is no limit
+	Limit *int64 `json:"limit"`
+}
+
+func newUsageLimit(used, limit int64) usageLimit {
+	u := usageLimit{Used: used}
+	if limit > 0 {
+		u.Limit = &limit
+	}
+	return u
+}
+
+// getUsageHandler returns what the user stores along with the limits of their role.
+func (app *application) getUsageHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	usage, err := userUsage(r.Context(), app.DB, user)
+	if err != nil {
+		log.Printf("Failed to fetch usage of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	q := app.quotas[user.Role]
+	respondWithJSON(w, http.StatusOK, &struct {
+		Role        string     `json:"role"`
+		Notes       usageLimit `json:"notes"`
+		Bytes       usageLimit `json:"bytes"`
+		NotesPerDay usageLimit `json:"notes_per_day"`
+	}{
+		Role:        user.Role,
+		Notes:       newUsageLimit(usage.Notes, q.Notes),
+		Bytes:       newUsageLimit(usage.Bytes, q.Bytes),
+		NotesPerDay: newUsageLimit(usage.NotesSince, q.NotesPerDay),
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+func TestDailyLimitCountsDeletedNotes(t *testing.T) {
+	app := newTestApp(t)
+	app.quotas = map[string]quota{userRoleUser: {NotesPerDay: 2}}
+	user := createTestUser(t, app, "ann", "ann@example.com")
+
+	for i := 0; i < 2; i++ {
+		note := createTestNote(t, app, user, "Hello", "")
+		if err := app.DB.DeleteNote(context.Background(), note.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	if app.checkQuota(w, httptest.NewRequest(http.MethodPost, "/notes", nil), user, true, 5) {
+		t.Fatal("daily limit was reset by deleting notes")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("responded with %d and Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestCreateNoteWithinQuotaIsAtomic(t *testing.T) {
+	app := newTestApp(t)
+	app.quotas = map[string]quota{userRoleUser: {Notes: 3}}
+	user := createTestUser(t, app, "ann", "ann@example.com")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			app.createNoteWithinQuota(w, httptest.NewRequest(http.MethodPost, "/notes", nil), user, database.CreateNoteParams{
+				ID:         utils.GenerateUniqueId(),
+				Author:     user.Username,
+				Message:    "Hello",
+				UserID:     user.ID,
+				Visibility: visibilityPublic,
+				Format:     formatPlain,
+			})
+		}()
+	}
+	wg.Wait()
+
+	usage, err := userUsage(context.Background(), app.DB, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Notes != 3 {
+		t.Errorf("user has %d notes, want 3", usage.Notes)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/ratelimit"
+)
+
+// defaultRateLimits apply unless RATE_LIMITS sets other limits for the same patterns.
+// The "*" limit applies to all routes without a limit of their own.
+const defaultRateLimits = "*=300/1m; POST /notes=30/1m; POST /users=10/1h; POST /users/auth=10/1m"
+
+// sqliteRateLimitStore keeps buckets in the database, so that processes sharing it share the limits.
+type sqliteRateLimitStore struct {
+	DB *database.Queries
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func (s *sqliteRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	now := time.Now()
+	s.prune(ctx, now)
+
+	row, err := s.DB.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Requests),
+		Now:   float64(now.UnixNano()) / 1e9,
+		Rate:  limit.Rate(),
+	})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.NewResult(limit, row.Tokens, row.Allowed != 0), nil
+}
+
+// prune deletes buckets that weren't used for a day at most once a minute.
+func (s *sqliteRateLimitStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	if err := s.DB.DeleteStaleRateLimits(ctx, float64(now.Add(-24*time.Hour).Unix())); err != nil {
+		log.Printf("Failed to delete stale rate limits: %s", err)
+	}
+}
+
+// loadRateLimits reads limits from RATE_LIMITS on top of the defaults. Buckets are kept
+// in memory, or in the database if RATE_LIMIT_STORE is "sqlite".
+func (app *application) loadRateLimits() {
+	limits, err := ratelimit.ParseLimits(defaultRateLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	custom, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %s", err)
+	}
+	for pattern, limit := range custom {
+		limits[pattern] = limit
+	}
+	app.rateLimits = limits
+
+	if os.Getenv("RATE_LIMIT_STORE") == "sqlite" {
+		app.rateLimitStore = &sqliteRateLimitStore{DB: app.DB}
+	} else {
+		app.rateLimitStore = ratelimit.NewMemoryStore()
+	}
+}
+
+// rateLimit limits requests to the routes of the mux. Every route pattern has a bucket
+// per identity: the user for authenticated requests and the client IP otherwise.
+func (app *application) rateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		limit, ok := app.rateLimits[pattern]
+		if !ok {
+			pattern = "*"
+			limit, ok = app.rateLimits[pattern]
+		}
+		if !ok {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := app.rateLimitStore.Take(r.Context(), pattern+" "+app.rateLimitIdentity(r), limit)
+		if err != nil {
+			// Don't take the service down along with the store
+			log.Printf("Failed to check rate limit: %s", err)
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(int(limit.Period.Seconds())))
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitIdentity returns who the request is counted against. Requests with invalid
+// credentials are counted against the client IP, so that made up tokens don't get buckets of their own.
+func (app *application) rateLimitIdentity(r *http.Request) string {
+	if token := auth.BearerToken(r.Header); auth.IsOAuthAccessToken(token) {
+		// Tokens of a user share the bucket with their sessions
+		if stored, active := app.activeOAuthToken(r.Context(), token); active && stored.Kind == "access" {
+			return "user:" + stored.UserID
+		}
+		return "ip:" + clientIP(r)
+	}
+	if hasCredentials(r) {
+		if claims, _, err := auth.ValidateRequest(r); err == nil && claims.UserID != "" {
+			return "user:" + claims.UserID
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/ratelimit"
+)
+
+func newRateLimitedHandler(t *testing.T, app *application, limits string) http.Handler {
+	t.Helper()
+	var err error
+	app.rateLimits, err = ratelimit.ParseLimits(limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.rateLimitStore = ratelimit.NewMemoryStore()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {})
+	return app.rateLimit(mux)
+}
+
+func requestWithToken(handler http.Handler, token string) int {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestRateLimitCountsInvalidTokensAgainstIP(t *testing.T) {
+	app := newTestApp(t)
+	handler := newRateLimitedHandler(t, app, "*=2/1m")
+
+	for i := 0; i < 2; i++ {
+		if code := requestWithToken(handler, "anat_"+randomTestString(t)); code != http.StatusOK {
+			t.Fatalf("request %d responded with %d", i+1, code)
+		}
+	}
+	if code := requestWithToken(handler, "anat_"+randomTestString(t)); code != http.StatusTooManyRequests {
+		t.Errorf("request with a new made up token responded with %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitCountsTokensAgainstUser(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	client := createTestOAuthClient(t, app, user)
+	code, verifier := createTestOAuthCode(t, app, client, user)
+	_, tokens := requestToken(t, app, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	if identity := app.rateLimitIdentity(r); identity != "user:"+user.ID {
+		t.Errorf("identity of a valid token = %q, want %q", identity, "user:"+user.ID)
+	}
+
+	// Once the IP is out of requests, the user still has their own bucket
+	handler := newRateLimitedHandler(t, app, "*=1/1m")
+	requestWithToken(handler, "anat_"+randomTestString(t))
+	if code := requestWithToken(handler, tokens.AccessToken); code != http.StatusOK {
+		t.Errorf("request with a valid token responded with %d, want %d", code, http.StatusOK)
+	}
+}
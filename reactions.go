@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// reaction is the number of users who reacted to a note with an emoji.
+type reaction struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+	// Reacted is true if the current user is one of them
+	Reacted bool `json:"reacted"`
+}
+
+// noteResponse is a note along with its reactions and the number of direct replies.
+type noteResponse struct {
+	database.Note
+	Reactions  []reaction `json:"reactions"`
+	ReplyCount int64      `json:"reply_count"`
+	// MessageHTML is the message rendered to HTML, if requested
+	MessageHTML string `json:"message_html,omitempty"`
+}
+
+// noteResponses adds reactions and reply counts to the notes, and messages rendered to HTML
+// if html is true. The user is empty for anonymous requests.
+func (app *application) noteResponses(ctx context.Context, notes []database.Note, user database.User, html bool) ([]noteResponse, error) {
+	result := make([]noteResponse, len(notes))
+	for i, note := range notes {
+		rows, err := app.DB.FetchNoteReactions(ctx, database.FetchNoteReactionsParams{
+			ViewerID: user.ID,
+			NoteID:   note.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		reactions := make([]reaction, len(rows))
+		for j, row := range rows {
+			reactions[j] = reaction{Emoji: row.Emoji, Count: row.Count, Reacted: row.Reacted == 1}
+		}
+
+		replyCount, err := app.DB.CountVisibleReplies(ctx, database.CountVisibleRepliesParams{
+			ParentID: note.ID,
+			ViewerID: user.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = noteResponse{Note: note, Reactions: reactions, ReplyCount: replyCount}
+		if html {
+			result[i].MessageHTML, err = app.renderNote(note)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// validEmoji returns true if s looks like a single emoji, including sequences
+// joined with ZWJ and ones with skin tone modifiers or variation selectors.
+func validEmoji(s string) bool {
+	if s == "" || len(s) > 32 || !utf8.ValidString(s) {
+		return false
+	}
+	symbols := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbols++
+		case r == '\u200d', r == '\ufe0f', r == '\u20e3', unicode.Is(unicode.Sk, r):
+		default:
+			return false
+		}
+	}
+	return symbols > 0
+}
+
+// setReaction returns a handler adding or removing the current user's reaction to a note.
+// Both are idempotent.
+func (app *application) setReaction(add bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		note, _, ok := app.fetchNoteForUser(w, r, user)
+		if !ok {
+			return
+		}
+
+		if add && app.hasBlocked(r.Context(), note.UserID, user.ID) {
+			http.Error(w, errBlockedMsg, http.StatusForbidden)
+			return
+		}
+
+		emoji := r.PathValue("emoji")
+		if !validEmoji(emoji) {
+			http.Error(w, "Reaction must be an emoji", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if add {
+			var added int64
+			added, err = app.DB.AddReaction(r.Context(), database.AddReactionParams{
+				NoteID: note.ID,
+				UserID: user.ID,
+				Emoji:  emoji,
+			})
+			if added > 0 {
+				app.notify(r.Context(), note.UserID, user.ID, notificationReaction, note.ID, emoji)
+			}
+		} else {
+			err = app.DB.DeleteReaction(r.Context(), database.DeleteReactionParams{
+				NoteID: note.ID,
+				UserID: user.ID,
+				Emoji:  emoji,
+			})
+		}
+		if err != nil {
+			log.Printf("Failed to update reaction of user %q to note %q: %s", user.ID, note.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
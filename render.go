@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// Note message formats
+const (
+	// Plain messages are shown as they are written
+	formatPlain = "plain"
+	// Markdown messages are written in CommonMark
+	formatMarkdown = "markdown"
+)
+
+func validFormat(format string) bool {
+	return format == formatPlain || format == formatMarkdown
+}
+
+// wantsHTML returns true if the request asks for messages rendered to HTML with ?render=html.
+func wantsHTML(r *http.Request) bool {
+	return r.URL.Query().Get("render") == "html"
+}
+
+// renderMessage returns the message of the note as sanitized HTML. Every change of
+// the message or its format bumps the revision, so the output is cached by it.
+func (app *application) renderMessage(id, message, format string, revision int64) (string, error) {
+	return app.renderer.Render(id+":"+strconv.FormatInt(revision, 10), message, format == formatMarkdown)
+}
+
+// renderNote is renderMessage for a note.
+func (app *application) renderNote(note database.Note) (string, error) {
+	return app.renderMessage(note.ID, note.Message, note.Format, note.Revision)
+}
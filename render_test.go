@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEditedNoteIsRenderedAgain(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, user, "**Hello**", "")
+
+	out, err := app.renderNote(note)
+	if err != nil || out != "<p>**Hello**</p>\n" {
+		t.Fatalf("rendered %q: %v", out, err)
+	}
+
+	r := httptest.NewRequest(http.MethodPatch, "/note/"+note.ID, strings.NewReader(`{"message":"**Bye**","format":"markdown"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", note.ID)
+	w := httptest.NewRecorder()
+	app.updateNoteHandler(w, r, user)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update responded with %d: %s", w.Code, w.Body)
+	}
+
+	edited, err := app.DB.FetchNoteByID(context.Background(), note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = app.renderNote(edited)
+	if err != nil || out != "<p><strong>Bye</strong></p>\n" {
+		t.Errorf("edited note rendered %q, want the new message as markdown: %v", out, err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// Replies are notes with a parent note. Threads can be arbitrarily deep and are paginated
+// by the direct replies, each of which comes with its own replies down to maxReplyDepth.
+// A page holds at most maxReplyNodes replies in total.
+const (
+	defaultRepliesLimit = 20
+	maxRepliesLimit     = 100
+	maxReplyDepth       = 8
+	maxReplyNodes       = 500
+)
+
+// replyNode is a reply in a thread. Depth is 1 for direct replies to the requested note.
+type replyNode struct {
+	noteResponse
+	Depth   int         `json:"depth"`
+	Replies []replyNode `json:"replies,omitempty"`
+	// HasMore is true if some of the replies were left out of the page,
+	// they can be fetched from the thread of this reply
+	HasMore bool `json:"has_more,omitempty"`
+}
+
+// getRepliesHandler responds with a page of the thread under the note, either as a tree
+// or, with ?view=flat, as a list in reading order.
+func (app *application) getRepliesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	note, err := app.DB.FetchNoteByID(r.Context(), id)
+	if err != nil || !app.canViewNote(r.Context(), note, user) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	limit, offset, err := parsePage(r, defaultRepliesLimit, maxRepliesLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := app.DB.CountVisibleReplies(r.Context(), database.CountVisibleRepliesParams{
+		ParentID: note.ID,
+		ViewerID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to count replies to note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	replies, err := app.DB.FetchReplies(r.Context(), database.FetchRepliesParams{
+		ParentID: note.ID,
+		ViewerID: user.ID,
+		Limit:    int64(limit),
+		Offset:   int64(offset),
+	})
+	if err != nil {
+		log.Printf("Failed to fetch replies to note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	budget := maxReplyNodes
+	tree, err := app.replyTree(r.Context(), replies, 1, user, wantsHTML(r), &budget)
+	if err != nil {
+		log.Printf("Failed to fetch thread of note %q: %s", note.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("view") == "flat" {
+		tree = flattenReplies(tree, nil)
+	}
+	if tree == nil {
+		tree = []replyNode{}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total   int64       `json:"total"`
+		Limit   int         `json:"limit"`
+		Offset  int         `json:"offset"`
+		Replies []replyNode `json:"replies"`
+	}{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		Replies: tree,
+	})
+}
+
+// parsePage reads ?limit and ?offset of the request.
+func parsePage(r *http.Request, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	limit, offset = defaultLimit, 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, errors.New("Limit must be between 1 and " + strconv.Itoa(maxLimit))
+		}
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("Offset must be a non-negative number")
+		}
+	}
+	return limit, offset, nil
+}
+
+// replyTree builds the thread under the replies, which are at the given depth, taking
+// at most budget replies. Replies of users muted or blocked by the user are left out
+// along with their own replies.
+func (app *application) replyTree(ctx context.Context, replies []database.Note, depth int, user database.User, html bool, budget *int) ([]replyNode, error) {
+	replies = replies[:min(len(replies), *budget)]
+	*budget -= len(replies)
+
+	responses, err := app.noteResponses(ctx, replies, user, html)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]replyNode, len(responses))
+	for i, response := range responses {
+		nodes[i] = replyNode{noteResponse: response, Depth: depth}
+		if response.ReplyCount == 0 {
+			continue
+		}
+
+		// Deeper replies are only counted, so that the page has a bounded size
+		if depth >= maxReplyDepth || *budget == 0 {
+			visible, err := app.DB.CountVisibleReplies(ctx, database.CountVisibleRepliesParams{
+				ParentID: response.ID,
+				ViewerID: user.ID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			nodes[i].HasMore = visible > 0
+			continue
+		}
+
+		children, err := app.DB.FetchVisibleReplies(ctx, database.FetchVisibleRepliesParams{
+			ParentID: response.ID,
+			ViewerID: user.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		nodes[i].HasMore = len(children) > *budget
+		nodes[i].Replies, err = app.replyTree(ctx, children, depth+1, user, html, budget)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// flattenReplies appends the thread to the list depth-first, so that every reply follows its parent.
+func flattenReplies(nodes []replyNode, list []replyNode) []replyNode {
+	for _, node := range nodes {
+		children := node.Replies
+		node.Replies = nil
+		list = append(list, node)
+		list = flattenReplies(children, list)
+	}
+	return list
+}
+
+// errThreadHasOtherAuthors is returned when a thread can't be removed because of replies of other users.
+var errThreadHasOtherAuthors = errors.New("thread has replies of other users")
+
+// removeThread deletes the note along with all replies to it in one transaction,
+// and then placeholders of deleted parents that are left without replies.
+// Unless othersAllowed is true, threads with replies of other users are left alone.
+func (app *application) removeThread(ctx context.Context, note database.Note, othersAllowed bool) error {
+	tx, err := app.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := app.DB.WithTx(tx)
+
+	if !othersAllowed {
+		other, err := threadHasOtherAuthors(ctx, qtx, note.ID, note.UserID)
+		if err != nil {
+			return err
+		}
+		if other {
+			return errThreadHasOtherAuthors
+		}
+	}
+	if err := deleteThread(ctx, qtx, note.ID); err != nil {
+		return err
+	}
+	if err := pruneDeletedParents(ctx, qtx, note.ParentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteThread deletes the note along with all replies to it.
+func deleteThread(ctx context.Context, db *database.Queries, id string) error {
+	replies, err := db.FetchAllReplies(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err := deleteThread(ctx, db, reply.ID); err != nil {
+			return err
+		}
+	}
+	return db.DeleteNote(ctx, id)
+}
+
+// threadHasOtherAuthors returns true if any reply in the thread of the note was written by
+// someone other than the user. Guests can't be told apart, so their replies always count.
+func threadHasOtherAuthors(ctx context.Context, db *database.Queries, id, userID string) (bool, error) {
+	replies, err := db.FetchAllReplies(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	for _, reply := range replies {
+		if reply.UserID == "" || reply.UserID != userID {
+			return true, nil
+		}
+		if other, err := threadHasOtherAuthors(ctx, db, reply.ID, userID); err != nil || other {
+			return other, err
+		}
+	}
+	return false, nil
+}
+
+// pruneDeletedParents deletes placeholders of deleted notes, starting from the given one
+// and going up the thread, once they have no replies left.
+func pruneDeletedParents(ctx context.Context, db *database.Queries, id string) error {
+	for id != "" {
+		note, err := db.FetchNoteByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if note.Deleted == 0 {
+			return nil
+		}
+
+		replies, err := db.CountReplies(ctx, id)
+		if err != nil {
+			return err
+		}
+		if replies > 0 {
+			return nil
+		}
+		if err := db.DeleteNote(ctx, id); err != nil {
+			return err
+		}
+		id = note.ParentID
+	}
+	return nil
+}
+
+// setThreadVisibility changes visibility of all replies to the note.
+func (app *application) setThreadVisibility(ctx context.Context, id, visibility string) error {
+	replies, err := app.DB.FetchAllReplies(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		err := app.DB.SetNoteVisibility(ctx, database.SetNoteVisibilityParams{
+			Visibility: visibility,
+			ID:         reply.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := app.setThreadVisibility(ctx, reply.ID, visibility); err != nil {
+			return err
+		}
+	}
+	return nil
+}
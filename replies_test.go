@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+type repliesPage struct {
+	Total   int64       `json:"total"`
+	Replies []replyNode `json:"replies"`
+}
+
+func fetchReplies(t *testing.T, app *application, note database.Note, viewer database.User) repliesPage {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/note/"+note.ID+"/replies", nil)
+	r.SetPathValue("id", note.ID)
+	w := httptest.NewRecorder()
+	app.getRepliesHandler(w, r, viewer)
+	if w.Code != http.StatusOK {
+		t.Fatalf("replies responded with %d: %s", w.Code, w.Body)
+	}
+
+	var page repliesPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestRepliesTotalLeavesOutMutedUsers(t *testing.T) {
+	app := newTestApp(t)
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	bob := createTestUser(t, app, "bob", "bob@example.com")
+	note := createTestNote(t, app, ann, "Hello", "")
+	createTestNote(t, app, ann, "First", note.ID)
+	createTestNote(t, app, bob, "Second", note.ID)
+
+	err := app.DB.AddRestriction(context.Background(), database.AddRestrictionParams{
+		UserID:   ann.ID,
+		TargetID: bob.ID,
+		Kind:     restrictionMute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := fetchReplies(t, app, note, ann)
+	if page.Total != 1 || len(page.Replies) != 1 {
+		t.Errorf("total = %d with %d replies, want 1 with 1 reply", page.Total, len(page.Replies))
+	}
+	if page := fetchReplies(t, app, note, bob); page.Total != 2 {
+		t.Errorf("total for another user = %d, want 2", page.Total)
+	}
+}
+
+func TestRepliesCutOffDeepThreads(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	root := createTestNote(t, app, user, "Hello", "")
+	parent := root
+	for i := 0; i < maxReplyDepth+2; i++ {
+		parent = createTestNote(t, app, user, "Reply", parent.ID)
+	}
+
+	page := fetchReplies(t, app, root, user)
+	depth, nodes := 0, page.Replies
+	for len(nodes) > 0 {
+		node := nodes[0]
+		depth = node.Depth
+		if node.HasMore != (depth == maxReplyDepth) {
+			t.Errorf("has_more = %t at depth %d", node.HasMore, depth)
+		}
+		nodes = node.Replies
+	}
+	if depth != maxReplyDepth {
+		t.Errorf("thread is %d replies deep, want %d", depth, maxReplyDepth)
+	}
+}
+
+func TestRepliesCutOffLargeThreads(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	root := createTestNote(t, app, user, "Hello", "")
+	first := createTestNote(t, app, user, "First", root.ID)
+	for i := 0; i < maxReplyNodes; i++ {
+		createTestNote(t, app, user, "Reply", first.ID)
+	}
+	second := createTestNote(t, app, user, "Second", root.ID)
+	createTestNote(t, app, user, "Reply", second.ID)
+
+	page := fetchReplies(t, app, root, user)
+	if len(page.Replies) != 2 {
+		t.Fatalf("got %d direct replies, want 2", len(page.Replies))
+	}
+	if n := len(page.Replies[0].Replies); n != maxReplyNodes-2 || !page.Replies[0].HasMore {
+		t.Errorf("first reply has %d replies with has_more = %t, want %d with has_more", n, page.Replies[0].HasMore, maxReplyNodes-2)
+	}
+	if n := len(page.Replies[1].Replies); n != 0 || !page.Replies[1].HasMore {
+		t.Errorf("second reply has %d replies with has_more = %t, want none with has_more", n, page.Replies[1].HasMore)
+	}
+}
+
+func hardDeleteNote(app *application, note database.Note, user database.User, editToken string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodDelete, "/note/"+note.ID+"?hard=true", nil)
+	r.SetPathValue("id", note.ID)
+	if editToken != "" {
+		r.Header.Set(editTokenHeader, editToken)
+	}
+	w := httptest.NewRecorder()
+	app.deleteNoteHandler(w, r, user)
+	return w
+}
+
+func TestHardDeleteKeepsRepliesOfOtherUsers(t *testing.T) {
+	app := newTestApp(t)
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	bob := createTestUser(t, app, "bob", "bob@example.com")
+	note := createTestNote(t, app, ann, "Hello", "")
+	own := createTestNote(t, app, ann, "Also", note.ID)
+	reply := createTestNote(t, app, bob, "Hi", own.ID)
+
+	if w := hardDeleteNote(app, note, ann, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("hard delete of a thread with replies of others responded with %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if _, err := app.DB.FetchNoteByID(context.Background(), reply.ID); err != nil {
+		t.Fatalf("reply of another user was deleted: %s", err)
+	}
+
+	ann.Role = userRoleModerator
+	if w := hardDeleteNote(app, note, ann, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("hard delete by a moderator responded with %d: %s", w.Code, w.Body)
+	}
+	for _, id := range []string{note.ID, own.ID, reply.ID} {
+		if _, err := app.DB.FetchNoteByID(context.Background(), id); err == nil {
+			t.Errorf("note %q of the thread wasn't deleted", id)
+		}
+	}
+}
+
+func TestHardDeleteOfOwnThread(t *testing.T) {
+	app := newTestApp(t)
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	note := createTestNote(t, app, ann, "Hello", "")
+	reply := createTestNote(t, app, ann, "Also", note.ID)
+
+	if w := hardDeleteNote(app, note, ann, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("hard delete of an own thread responded with %d: %s", w.Code, w.Body)
+	}
+	if _, err := app.DB.FetchNoteByID(context.Background(), reply.ID); err == nil {
+		t.Error("reply wasn't deleted with the thread")
+	}
+}
+
+func TestGuestCantHardDeleteReplies(t *testing.T) {
+	app := newTestApp(t)
+	app.anonymousPosting = anonymousUnverified
+	guest := createGuestNote(t, app, `{"message":"Hello"}`)
+	note, err := app.DB.FetchNoteByID(context.Background(), guest.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createGuestNote(t, app, `{"message":"Hi","parent_id":"`+note.ID+`"}`)
+
+	if w := hardDeleteNote(app, note, database.User{}, guest.EditToken); w.Code != http.StatusForbidden {
+		t.Errorf("hard delete by a guest responded with %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestReplyCountLeavesOutMutedUsers(t *testing.T) {
+	app := newTestApp(t)
+	ann := createTestUser(t, app, "ann", "ann@example.com")
+	bob := createTestUser(t, app, "bob", "bob@example.com")
+	note := createTestNote(t, app, ann, "Hello", "")
+	createTestNote(t, app, ann, "First", note.ID)
+	createTestNote(t, app, bob, "Second", note.ID)
+
+	err := app.DB.AddRestriction(context.Background(), database.AddRestrictionParams{
+		UserID:   ann.ID,
+		TargetID: bob.ID,
+		Kind:     restrictionMute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses, err := app.noteResponses(context.Background(), []database.Note{note}, ann, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if responses[0].ReplyCount != 1 {
+		t.Errorf("reply_count = %d, want 1", responses[0].ReplyCount)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// Kinds of restrictions a user can put on another user.
+// Notes of both muted and blocked users are left out of all listings of the user,
+// and blocked users additionally can't interact with the user.
+const (
+	restrictionMute  = "mute"
+	restrictionBlock = "block"
+)
+
+// errBlockedMsg is the response to interactions with a user who blocked the current user
+const errBlockedMsg = "You can't interact with this user"
+
+// hasBlocked returns true if the blocker blocked the user.
+func (app *application) hasBlocked(ctx context.Context, blockerID, userID string) bool {
+	if blockerID == "" || userID == "" {
+		return false
+	}
+	blocked, err := app.DB.HasBlocked(ctx, database.HasBlockedParams{
+		UserID:   blockerID,
+		TargetID: userID,
+	})
+	if err != nil {
+		log.Printf("Failed to check if user %q blocked user %q: %s", blockerID, userID, err)
+		return false
+	}
+	return blocked > 0
+}
+
+// setRestriction returns a handler adding or removing a restriction on the user from the path.
+// Blocking also removes follows and shared notes between the two users.
+func (app *application) setRestriction(kind string, add bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		target, err := app.DB.GetUserByUsername(r.Context(), r.PathValue("username"))
+		if err != nil {
+			http.Error(w, "User does not exist", http.StatusNotFound)
+			return
+		}
+		if target.ID == user.ID {
+			http.Error(w, "You can't "+kind+" yourself", http.StatusBadRequest)
+			return
+		}
+
+		if !add {
+			err = app.DB.DeleteRestriction(r.Context(), database.DeleteRestrictionParams{
+				UserID:   user.ID,
+				TargetID: target.ID,
+				Kind:     kind,
+			})
+			if err != nil {
+				log.Printf("Failed to remove %s of user %q by user %q: %s", kind, target.ID, user.ID, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		err = app.DB.AddRestriction(r.Context(), database.AddRestrictionParams{
+			UserID:   user.ID,
+			TargetID: target.ID,
+			Kind:     kind,
+		})
+		if err == nil && kind == restrictionBlock {
+			err = app.DB.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+				UserA: user.ID,
+				UserB: target.ID,
+			})
+			if err == nil {
+				err = app.DB.DeleteCollaborationsBetween(r.Context(), database.DeleteCollaborationsBetweenParams{
+					UserA: user.ID,
+					UserB: target.ID,
+				})
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to %s user %q by user %q: %s", kind, target.ID, user.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("User %q added %s of user %q", user.ID, kind, target.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type restrictedUserResponse struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+// getRestrictedUsersHandler returns a handler listing users the current user muted or blocked.
+func (app *application) getRestrictedUsersHandler(kind string) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		rows, err := app.DB.FetchRestrictedUsers(r.Context(), database.FetchRestrictedUsersParams{
+			UserID: user.ID,
+			Kind:   kind,
+		})
+		if err != nil {
+			log.Printf("Failed to fetch %s list of user %q: %s", kind, user.ID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]restrictedUserResponse, len(rows))
+		for i, row := range rows {
+			users[i] = restrictedUserResponse(row)
+		}
+
+		respondWithJSON(w, http.StatusOK, &struct {
+			Total int                      `json:"total"`
+			Users []restrictedUserResponse `json:"users"`
+		}{
+			Total: len(users),
+			Users: users,
+		})
+	}
+}
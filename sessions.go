@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// How often last seen time of a session is updated
+const sessionTouchInterval = time.Minute
+
+type sessionIDKey struct{}
+
+// currentSessionID returns the ID of the session the request was authenticated with.
+func currentSessionID(r *http.Request) string {
+	id, _ := r.Context().Value(sessionIDKey{}).(string)
+	return id
+}
+
+// respondWithSession records a new login of the user and responds with a token bound to it.
+func (app *application) respondWithSession(w http.ResponseWriter, r *http.Request, userID, email string) {
+	app.respondWithNewSession(w, r, userID, email, cookieMode(r))
+}
+
+// respondWithNewSession is like respondWithSession, but lets the caller decide
+// whether the token is kept in a cookie.
+func (app *application) respondWithNewSession(w http.ResponseWriter, r *http.Request, userID, email string, withCookie bool) {
+	session, err := app.DB.CreateSession(r.Context(), database.CreateSessionParams{
+		ID:        utils.GenerateUniqueId(),
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		log.Printf("Failed to create a session for user %q: %s", userID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("New session %q for user %q", session.ID, userID)
+	if withCookie {
+		auth.RespondWithCookie(w, userID, email, session.ID)
+		return
+	}
+	auth.RespondWithJWT(w, userID, email, session.ID)
+}
+
+// cookieMode returns true if the client asked to keep the token in a cookie, e.g. POST /users/auth?mode=cookie
+func cookieMode(r *http.Request) bool {
+	return r.URL.Query().Get("mode") == "cookie"
+}
+
+// checkSession returns false if the session doesn't belong to the user or was revoked.
+// Otherwise, it updates last seen time and IP address of the session.
+func (app *application) checkSession(ctx context.Context, sessionID, userID, ip string) bool {
+	session, err := app.DB.GetSession(ctx, sessionID)
+	if err != nil || session.UserID != userID || session.RevokedAt.Valid {
+		return false
+	}
+
+	err = app.DB.TouchSession(ctx, database.TouchSessionParams{
+		IpAddress:  ip,
+		ID:         sessionID,
+		LastSeenAt: time.Now().Add(-sessionTouchInterval).UTC().Format(timestampFormat),
+	})
+	if err != nil {
+		log.Printf("Failed to update session %q: %s", sessionID, err)
+	}
+	return true
+}
+
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	sessions, err := app.DB.FetchActiveSessions(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to fetch sessions of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	type sessionResponse struct {
+		ID         string `json:"id"`
+		UserAgent  string `json:"user_agent"`
+		IpAddress  string `json:"ip_address"`
+		CreatedAt  string `json:"created_at"`
+		LastSeenAt string `json:"last_seen_at"`
+		Current    bool   `json:"current"`
+	}
+
+	current := currentSessionID(r)
+	result := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		result[i] = sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IpAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == current,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &struct {
+		Total    int               `json:"total"`
+		Sessions []sessionResponse `json:"sessions"`
+	}{
+		Total:    len(result),
+		Sessions: result,
+	})
+}
+
+func (app *application) revokeSessionHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id := r.PathValue("id")
+
+	if !utils.ValidateId(id) {
+		msg := "Invalid session ID"
+		log.Printf(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := app.DB.RevokeSession(r.Context(), database.RevokeSessionParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to revoke session %q: %s", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		http.Error(w, "Session does not exist", http.StatusNotFound)
+		return
+	}
+
+	if id == currentSessionID(r) {
+		auth.ClearCookies(w)
+	}
+
+	log.Printf("User %q revoked session %q", user.ID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeAllSessionsHandler logs the user out everywhere, including the current session.
+func (app *application) revokeAllSessionsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := app.DB.RevokeAllSessions(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	auth.ClearCookies(w)
+
+	log.Printf("User %q revoked all sessions", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
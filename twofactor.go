@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/auth"
+	"github.com/chtozamm/annynotes-go/internal/database"
+	"github.com/chtozamm/annynotes-go/internal/utils"
+)
+
+// Issuer shown in authenticator apps
+const totpIssuer = "Annynotes"
+
+// Number of recovery codes issued when two-factor authentication is enabled
+const recoveryCodesCount = 10
+
+type twoFactorCode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (app *application) setupTwoFactorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	twoFactor, err := app.DB.GetTwoFactor(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to fetch two-factor settings of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err == nil && twoFactor.Enabled == 1 {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Failed to generate TOTP secret: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = app.DB.SetupTwoFactor(r.Context(), database.SetupTwoFactorParams{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		log.Printf("Failed to store TOTP secret of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %q started two-factor authentication setup", user.ID)
+	respondWithJSON(w, http.StatusOK, &struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}{
+		Secret: secret,
+		URI:    auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (app *application) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body twoFactorCode
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	twoFactor, err := app.DB.GetTwoFactor(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Two-factor authentication setup was not started", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to fetch two-factor settings of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if twoFactor.Enabled == 1 {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(twoFactor.Secret, body.Code, time.Now())
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, enabled, err := app.enableTwoFactor(r.Context(), user.ID, step)
+	if err != nil {
+		log.Printf("Failed to enable two-factor authentication for user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	log.Printf("User %q enabled two-factor authentication", user.ID)
+	respondWithJSON(w, http.StatusOK, &struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	})
+}
+
+func (app *application) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body twoFactorCode
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	twoFactor, err := app.DB.GetTwoFactor(r.Context(), user.ID)
+	if err != nil || twoFactor.Enabled != 1 {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	ok, err := app.checkSecondFactor(r.Context(), twoFactor, body)
+	if err != nil {
+		log.Printf("Failed to check second factor of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.DB.DeleteTwoFactor(r.Context(), user.ID); err != nil {
+		log.Printf("Failed to disable two-factor authentication for user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err := app.DB.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		log.Printf("Failed to delete recovery codes of user %q: %s", user.ID, err)
+	}
+
+	log.Printf("User %q disabled two-factor authentication", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSecondFactor responds with a challenge token instead of a session if the user has
+// two-factor authentication enabled, and returns false if the login can be completed without it.
+func (app *application) requireSecondFactor(w http.ResponseWriter, r *http.Request, user database.User) bool {
+	twoFactor, err := app.DB.GetTwoFactor(r.Context(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to fetch two-factor settings of user %q: %s", user.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+	if twoFactor.Enabled != 1 {
+		return false
+	}
+
+	challengeToken, err := auth.GenerateChallengeToken(user.ID, user.Email)
+	if err != nil {
+		log.Printf("Failed to generate challenge token: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+	respondWithJSON(w, http.StatusOK, &struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+	}{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+	})
+	return true
+}
+
+// verifyTwoFactorHandler exchanges a challenge token returned by authenticateUserHandler
+// and a TOTP or recovery code for an access token.
+func (app *application) verifyTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ChallengeToken string `json:"challenge_token"`
+		twoFactorCode
+	}
+
+	err := decodeJSONBody(w, r, &body)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			http.Error(w, mr.msg, mr.status)
+		} else {
+			log.Print(err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if body.ChallengeToken == "" || (body.Code == "" && body.RecoveryCode == "") {
+		msg := "Malformed request: expected payload to have challenge_token and code or recovery_code fields"
+		log.Print(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	claims, err := auth.ValidateChallengeToken(body.ChallengeToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	email := strings.ToLower(claims.Email)
+	if !app.beginLoginAttempt(w, r, email) {
+		return
+	}
+	defer app.endLoginAttempt(email, clientIP(r))
+
+	twoFactor, err := app.DB.GetTwoFactor(r.Context(), claims.UserID)
+	if err != nil || twoFactor.Enabled != 1 {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusUnauthorized)
+		return
+	}
+
+	ok, err := app.checkSecondFactor(r.Context(), twoFactor, body.twoFactorCode)
+	if err != nil {
+		log.Printf("Failed to check second factor of user %q: %s", claims.UserID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		log.Printf("Failed second factor attempt for %q from %s", email, clientIP(r))
+		app.recordFailedLogin(r.Context(), email, clientIP(r))
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	app.accountThrottle.Reset(email)
+	app.respondWithSession(w, r, claims.UserID, claims.Email)
+}
+
+// checkSecondFactor validates either a TOTP code, refusing codes from an already used
+// time step, or a recovery code, which is used up in the process.
+func (app *application) checkSecondFactor(ctx context.Context, twoFactor database.TwoFactor, code twoFactorCode) (bool, error) {
+	if code.Code != "" {
+		step, ok := auth.ValidateTOTP(twoFactor.Secret, code.Code, time.Now())
+		if !ok || step <= twoFactor.LastUsedStep {
+			return false, nil
+		}
+		// The step is only moved forward, so that concurrent requests can't both use the code
+		updated, err := app.DB.UpdateTwoFactorStep(ctx, database.UpdateTwoFactorStepParams{
+			LastUsedStep: step,
+			UserID:       twoFactor.UserID,
+		})
+		return err == nil && updated == 1, err
+	}
+
+	if code.RecoveryCode != "" {
+		used, err := app.DB.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   twoFactor.UserID,
+			CodeHash: auth.HashRecoveryCode(code.RecoveryCode),
+		})
+		if err != nil {
+			return false, err
+		}
+		if used == 1 {
+			log.Printf("User %q used a recovery code", twoFactor.UserID)
+		}
+		return used == 1, nil
+	}
+
+	return false, nil
+}
+
+// enableTwoFactor enables two-factor authentication of the user along with new recovery codes
+// in one transaction, so that it is never enabled without them, and returns the codes.
+// It returns false if two-factor authentication was already enabled.
+func (app *application) enableTwoFactor(ctx context.Context, userID string, step int64) ([]string, bool, error) {
+	tx, err := app.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+	qtx := app.DB.WithTx(tx)
+
+	enabled, err := qtx.EnableTwoFactor(ctx, database.EnableTwoFactorParams{
+		LastUsedStep: step,
+		UserID:       userID,
+	})
+	if err != nil || enabled == 0 {
+		return nil, false, err
+	}
+	codes, err := createRecoveryCodes(ctx, qtx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// createRecoveryCodes replaces recovery codes of the user with new ones and returns them.
+// Only hashes of the codes are stored.
+func createRecoveryCodes(ctx context.Context, db *database.Queries, userID string) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err := db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			ID:       utils.GenerateUniqueId(),
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chtozamm/annynotes-go/internal/database"
+)
+
+// testTOTPSecret is a base32-encoded secret for two-factor tests.
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// testTOTPCode returns the 6-digit code of the secret at the time, as an authenticator app would.
+func testTOTPCode(secret string, at time.Time) string {
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff%1000000)
+}
+
+func confirmTestTwoFactor(app *application, user database.User) *httptest.ResponseRecorder {
+	body := `{"code":"` + testTOTPCode(testTOTPSecret, time.Now()) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/users/me/2fa/confirm", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	app.confirmTwoFactorHandler(w, r, user)
+	return w
+}
+
+func setupTestTwoFactor(t *testing.T, app *application, user database.User) {
+	t.Helper()
+	_, err := app.DB.SetupTwoFactor(context.Background(), database.SetupTwoFactorParams{UserID: user.ID, Secret: testTOTPSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfirmTwoFactorCreatesRecoveryCodes(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	setupTestTwoFactor(t, app, user)
+
+	w := confirmTestTwoFactor(app, user)
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirm responded with %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.RecoveryCodes) != recoveryCodesCount {
+		t.Fatalf("got %d recovery codes, want %d: %v", len(body.RecoveryCodes), recoveryCodesCount, err)
+	}
+
+	// Confirming again doesn't replace the codes
+	if w := confirmTestTwoFactor(app, user); w.Code != http.StatusConflict {
+		t.Errorf("second confirm responded with %d, want %d", w.Code, http.StatusConflict)
+	}
+	var stored int
+	if err := app.conn.QueryRow("SELECT count(*) FROM recovery_codes WHERE user_id = ?", user.ID).Scan(&stored); err != nil || stored != recoveryCodesCount {
+		t.Errorf("%d recovery codes are stored, want %d: %v", stored, recoveryCodesCount, err)
+	}
+}
+
+func TestConfirmTwoFactorIsAtomic(t *testing.T) {
+	app := newTestApp(t)
+	user := createTestUser(t, app, "ann", "ann@example.com")
+	setupTestTwoFactor(t, app, user)
+
+	// Recovery codes can't be stored
+	if _, err := app.conn.Exec("DROP TABLE recovery_codes"); err != nil {
+		t.Fatal(err)
+	}
+	if w := confirmTestTwoFactor(app, user); w.Code != http.StatusInternalServerError {
+		t.Fatalf("confirm without recovery codes responded with %d: %s", w.Code, w.Body)
+	}
+
+	twoFactor, err := app.DB.GetTwoFactor(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twoFactor.Enabled != 0 {
+		t.Error("two-factor authentication was enabled without recovery codes")
+	}
+}